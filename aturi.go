@@ -0,0 +1,37 @@
+package ltbsky
+
+import (
+	"fmt"
+	"strings"
+)
+
+// atURI is a parsed AT-URI that points at a single record, such as
+// "at://did:plc:abc/app.bsky.feed.post/3k2a".
+type atURI struct {
+	repo       string
+	collection string
+	rkey       string
+}
+
+// parseATURI parses a record AT-URI into its repo, collection, and record key.
+func parseATURI(s string) (*atURI, error) {
+	rest, ok := strings.CutPrefix(s, "at://")
+	if !ok {
+		return nil, fmt.Errorf("invalid AT-URI %q: missing at:// prefix", s)
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid AT-URI %q: expected at://repo/collection/rkey", s)
+	}
+	return &atURI{
+		repo:       parts[0],
+		collection: parts[1],
+		rkey:       parts[2],
+	}, nil
+}
+
+func (u *atURI) String() string {
+	return fmt.Sprintf("at://%s/%s/%s", u.repo, u.collection, u.rkey)
+}
@@ -0,0 +1,59 @@
+package ltbsky
+
+import "testing"
+
+func TestParseATURI(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    atURI
+		wantErr bool
+	}{
+		{
+			name: "Post",
+			uri:  "at://did:plc:abc123/app.bsky.feed.post/3k2akbcd",
+			want: atURI{repo: "did:plc:abc123", collection: "app.bsky.feed.post", rkey: "3k2akbcd"},
+		},
+		{
+			name: "Handle repo",
+			uri:  "at://golang.org/app.bsky.feed.post/3k2akbcd",
+			want: atURI{repo: "golang.org", collection: "app.bsky.feed.post", rkey: "3k2akbcd"},
+		},
+		{
+			name:    "Missing prefix",
+			uri:     "https://bsky.app/profile/golang.org/post/3k2akbcd",
+			wantErr: true,
+		},
+		{
+			name:    "Missing rkey",
+			uri:     "at://did:plc:abc123/app.bsky.feed.post",
+			wantErr: true,
+		},
+		{
+			name:    "Too many parts",
+			uri:     "at://did:plc:abc123/app.bsky.feed.post/3k2akbcd/extra",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseATURI(tt.uri)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("wanted error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("wanted no error, got %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("wanted %+v, got %+v", tt.want, *got)
+			}
+			if got.String() != tt.uri {
+				t.Errorf("wanted String() '%s', got '%s'", tt.uri, got.String())
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ package ltbsky
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,11 +14,10 @@ import (
 	"image/gif"
 	"image/jpeg"
 	"image/png"
-	"io"
 	"log"
 	"net/http"
 	"os"
-	"regexp"
+	"sync"
 	"time"
 
 	"golang.org/x/image/draw"
@@ -25,16 +25,36 @@ import (
 
 // A Client for interacting with the Bluesky server.
 type Client struct {
-	server      string
-	handle      string
-	password    string
-	accessToken string
-	httpClient  *http.Client
+	server     string
+	handle     string
+	password   string
+	httpClient *http.Client
+
+	retry RetryPolicy
+
+	videoService           string
+	videoPollInterval      time.Duration
+	videoProcessingTimeout time.Duration
+
+	mu      sync.Mutex
+	session *Session
+	store   SessionStore
+}
+
+// A ClientOption configures optional Client behavior.
+type ClientOption func(*Client)
+
+// WithSessionStore makes the Client load its session from store before
+// logging in, and save the session to store after every login or refresh.
+func WithSessionStore(store SessionStore) ClientOption {
+	return func(c *Client) {
+		c.store = store
+	}
 }
 
 // NewClient creates a new Client instance with the provided server, handle,
 // and token.
-func NewClient(server, handle, password string) (*Client, error) {
+func NewClient(server, handle, password string, opts ...ClientOption) (*Client, error) {
 	if server == "" {
 		return nil, fmt.Errorf("server cannot be empty")
 	}
@@ -49,16 +69,40 @@ func NewClient(server, handle, password string) (*Client, error) {
 		handle:     handle,
 		password:   password,
 		httpClient: &http.Client{},
+		retry:      DefaultRetryPolicy,
+
+		videoService:           DefaultVideoService,
+		videoPollInterval:      defaultVideoPollInterval,
+		videoProcessingTimeout: defaultVideoProcessingTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
 	return c, nil
 }
 
 // PostBuilder is used to compose a post before sending it to the server.
 type PostBuilder struct {
-	content string
-	langs   []string
-	images  []*localImage
-	facets  []*facet
+	content  string
+	langs    []string
+	images   []*localImage
+	video    *localVideo
+	captions []*localCaption
+	facets   []*Facet
+	replyTo  string
+	reply    *ReplyRef
+	quoteOf  string
+	quote    *StrongRef
+
+	linkCard      *LinkCard
+	fetchLinkCard bool
+	firstLinkCard bool
+	removeEmbed   bool
+
+	replyRules    []ReplyRule
+	disableQuotes bool
+
+	selfLabels []selfLabel
 }
 
 // NewPostBuilder creates a new PostBuilder with the initial content.
@@ -104,13 +148,14 @@ func (pb *PostBuilder) AddImageFromBytes(data []byte, alt string) *PostBuilder {
 	return pb
 }
 
-func (pb *PostBuilder) buildFor(server string, c *http.Client) (*postRequest, error) {
+func (pb *PostBuilder) buildFor(ctx context.Context, server string, c HttpClient) (*postRequest, error) {
 	createdAt := time.Now().UTC().Format(time.RFC3339)
-	record := &record{
+	record := &PostRecord{
 		Type:      "app.bsky.feed.post",
 		Text:      pb.content,
 		CreatedAt: createdAt,
 		Langs:     pb.langs,
+		Labels:    pb.labels(),
 	}
 
 	// Load images from disk
@@ -125,13 +170,14 @@ func (pb *PostBuilder) buildFor(server string, c *http.Client) (*postRequest, er
 		}
 	}
 
+	pb.facets = nil
 	pb.parseLinks()
-	pb.parseMentions(server, c)
+	pb.parseMentions(ctx, server, c)
 	pb.parseTags()
 	if len(pb.facets) > 0 {
-		record.Facets = make([]facet, len(pb.facets))
+		record.Facets = make([]Facet, len(pb.facets))
 		for i, f := range pb.facets {
-			record.Facets[i] = facet{
+			record.Facets[i] = Facet{
 				Index:    f.Index,
 				Features: f.Features,
 			}
@@ -144,141 +190,112 @@ func (pb *PostBuilder) buildFor(server string, c *http.Client) (*postRequest, er
 	}, nil
 }
 
-func (pb *PostBuilder) parseLinks() {
-	// regex based on: https://docs.bsky.app/docs/advanced-guides/posts#mentions-and-links
-	url_regex := `(?:^|\s|\W)(?P<url>https?:\/\/(www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b([-a-zA-Z0-9()@:%_\+.~#?&//=]*[-a-zA-Z0-9@%_\+~#//=])?)`
-	r, err := regexp.Compile(url_regex)
-	if err != nil {
-		log.Printf("Error compiling regex: %v", err)
-		return
-	}
-	matches := r.FindAllSubmatchIndex([]byte(pb.content), -1)
-	for _, match := range matches {
-		start := match[2] // start position of the 'url' group
-		end := match[3]
-		link := pb.content[start:end]
-		f := &facet{
-			Features: []feature{
-				{Type: "app.bsky.richtext.facet#link", Uri: link},
-			},
-		}
-		f.Index.ByteStart = start
-		f.Index.ByteEnd = end
-		pb.facets = append(pb.facets, f)
-	}
+type postRequest struct {
+	Repo       string      `json:"repo"`
+	Collection string      `json:"collection"`
+	Record     *PostRecord `json:"record"`
 }
 
-type HttpClient interface {
-	Do(req *http.Request) (*http.Response, error)
+// A PostRecord is an app.bsky.feed.post record, as stored in a repo.
+type PostRecord struct {
+	Type      string    `json:"$type"`
+	Text      string    `json:"text"`
+	CreatedAt string    `json:"createdAt"`
+	Langs     []string  `json:"langs,omitempty"`
+	Facets    []Facet   `json:"facets,omitempty"`
+	Reply     *ReplyRef `json:"reply,omitempty"`
+	Embed     *Embed    `json:"embed,omitempty"`
+	// Labels are the content warnings the author put on the post.
+	Labels *SelfLabels `json:"labels,omitempty"`
 }
 
-func (pb *PostBuilder) parseMentions(server string, c HttpClient) {
-	// regex based on: https://atproto.com/specs/handle#handle-identifier-syntax
-	handle_regex := `(?:^|\s|\W)(?P<handle>@([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)`
-	r, err := regexp.Compile(handle_regex)
-	if err != nil {
-		log.Printf("Error compiling regex: %v", err)
-		return
-	}
-	matches := r.FindAllSubmatchIndex([]byte(pb.content), -1)
-
-	for _, match := range matches {
-		start := match[2] // start position of the 'handle' group
-		end := match[3]
-		handle := pb.content[start+1 : end] // +1 to skip the '@' character
-		url := fmt.Sprintf("%s/xrpc/com.atproto.identity.resolveHandle?handle=%s", server, handle)
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			log.Printf("Error creating request for handle %s: %v", handle, err)
-			continue
-		}
-		resp, err := c.Do(req)
-		if err != nil {
-			log.Printf("Error making request for handle %s: %v", handle, err)
-			continue
-		}
-		defer func() {
-			err = errors.Join(err, resp.Body.Close())
-		}()
+// An Embed is the media or quoted record embedded in a post. Which fields are
+// set depends on Type, such as "app.bsky.embed.images" or
+// "app.bsky.embed.recordWithMedia".
+type Embed struct {
+	Type        string          `json:"$type"`
+	Images      []*EmbedImage   `json:"images,omitempty"`
+	External    *EmbedExternal  `json:"external,omitempty"`
+	Video       *Blob           `json:"video,omitempty"`
+	Captions    []*VideoCaption `json:"captions,omitempty"`
+	Alt         string          `json:"alt,omitempty"`
+	AspectRatio *AspectRatio    `json:"aspectRatio,omitempty"`
+	// Record is the quoted record of an app.bsky.embed.record or
+	// app.bsky.embed.recordWithMedia embed.
+	Record *StrongRef `json:"-"`
+	// Media is the media shown with the quoted record of an
+	// app.bsky.embed.recordWithMedia embed.
+	Media *Embed `json:"media,omitempty"`
+}
 
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("Failed to resolve handle %s with status code: %d", handle, resp.StatusCode)
-			continue
-		}
-		b, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Printf("Error reading response body for handle %s: %v", handle, err)
-			continue
-		}
-		var resolveResponse struct {
-			Did string `json:"did"`
-		}
-		if err := json.Unmarshal(b, &resolveResponse); err != nil {
-			log.Printf("Error unmarshaling response for handle %s: %v", handle, err)
-			continue
-		}
-		f := &facet{
-			Features: []feature{
-				{Type: "app.bsky.richtext.facet#mention", Did: resolveResponse.Did},
-			},
+// embedJSON has the fields of Embed without its methods.
+type embedJSON Embed
+
+// MarshalJSON encodes the embed. The quoted record of a recordWithMedia embed
+// is wrapped in an app.bsky.embed.record embed, as the lexicon requires.
+func (e *Embed) MarshalJSON() ([]byte, error) {
+	var record any
+	if e.Record != nil {
+		record = e.Record
+		if e.Type == "app.bsky.embed.recordWithMedia" {
+			record = map[string]any{"$type": "app.bsky.embed.record", "record": e.Record}
 		}
-		f.Index.ByteStart = start
-		f.Index.ByteEnd = end
-		pb.facets = append(pb.facets, f)
-
 	}
+	return json.Marshal(struct {
+		*embedJSON
+		Record any `json:"record,omitempty"`
+	}{(*embedJSON)(e), record})
 }
 
-func (pb *PostBuilder) parseTags() {
-	tag_regex := `[\s^](?P<tag>#[^\d\s]\S*)\b`
-	r, err := regexp.Compile(tag_regex)
-	if err != nil {
-		log.Printf("Error compiling regex: %v", err)
-		return
-	}
-	matches := r.FindAllSubmatchIndex([]byte(pb.content), -1)
-	for _, match := range matches {
-		start := match[2] // start position of the tag group
-		end := match[3]
-		tag := pb.content[start+1 : end] // +1 to skip the '#' character
-		f := &facet{
-			Features: []feature{
-				{Type: "app.bsky.richtext.facet#tag", Tag: tag},
-			},
+// UnmarshalJSON decodes the embed, unwrapping the quoted record of a
+// recordWithMedia embed.
+func (e *Embed) UnmarshalJSON(b []byte) error {
+	var v struct {
+		*embedJSON
+		Record json.RawMessage `json:"record"`
+	}
+	v.embedJSON = (*embedJSON)(e)
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if len(v.Record) == 0 {
+		return nil
+	}
+	if e.Type == "app.bsky.embed.recordWithMedia" {
+		var wrapped struct {
+			Record *StrongRef `json:"record"`
+		}
+		if err := json.Unmarshal(v.Record, &wrapped); err != nil {
+			return err
 		}
-		f.Index.ByteStart = start
-		f.Index.ByteEnd = end
-		pb.facets = append(pb.facets, f)
+		e.Record = wrapped.Record
+		return nil
 	}
+	return json.Unmarshal(v.Record, &e.Record)
 }
 
-type postRequest struct {
-	Repo       string  `json:"repo"`
-	Collection string  `json:"collection"`
-	Record     *record `json:"record"`
+// An EmbedExternal is a link card.
+type EmbedExternal struct {
+	URI         string `json:"uri"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Thumb       *Blob  `json:"thumb,omitempty"`
 }
 
-type record struct {
-	Type      string   `json:"$type"`
-	Text      string   `json:"text"`
-	CreatedAt string   `json:"createdAt"`
-	Langs     []string `json:"langs,omitempty"`
-	Facets    []facet  `json:"facets,omitempty"`
-	Embed     *struct {
-		Type   string   `json:"$type"`
-		Images []*image `json:"images,omitempty"`
-	} `json:"embed,omitempty"`
-}
-
-type facet struct {
+// A Facet marks a range of a post's text as a link, mention, or tag. The
+// range is given in UTF-8 bytes.
+type Facet struct {
 	Index struct {
 		ByteStart int `json:"byteStart"`
 		ByteEnd   int `json:"byteEnd"`
 	} `json:"index"`
-	Features []feature `json:"features"`
+	Features []FacetFeature `json:"features"`
 }
 
-type feature struct {
+// A FacetFeature is what a facet's text links to. Type is
+// "app.bsky.richtext.facet#link" with Uri set, "#mention" with Did set, or
+// "#tag" with Tag set.
+type FacetFeature struct {
 	Type   string `json:"$type"`
 	Did    string `json:"did,omitempty"`
 	Handle string `json:"handle,omitempty"`
@@ -286,16 +303,21 @@ type feature struct {
 	Uri    string `json:"uri,omitempty"`
 }
 
-type image struct {
-	Alt         string      `json:"alt"`
-	Image       *imageEmbed `json:"image"`
-	AspectRatio *struct {
-		Width  int `json:"width"`
-		Height int `json:"height"`
-	} `json:"aspectRatio"`
+// An EmbedImage is an image embedded in a post.
+type EmbedImage struct {
+	Alt         string       `json:"alt"`
+	Image       *Blob        `json:"image"`
+	AspectRatio *AspectRatio `json:"aspectRatio"`
 }
 
-type imageEmbed struct {
+// AspectRatio is the width and height of an image or video.
+type AspectRatio struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// A Blob is a reference to an uploaded file, such as an image.
+type Blob struct {
 	Type string `json:"$type"`
 	Ref  *struct {
 		Link string `json:"$link,omitempty"`
@@ -316,179 +338,143 @@ func (l *localImage) String() string {
 
 // Post creates a new public post with the given content.
 func (c *Client) Post(pb *PostBuilder) (string, error) {
-	err := c.auth()
+	return c.PostContext(context.Background(), pb)
+}
+
+// PostContext is like Post but uses ctx for every request made to the server,
+// including handle resolution and image uploads.
+func (c *Client) PostContext(ctx context.Context, pb *PostBuilder) (string, error) {
+	ref, err := c.createPost(ctx, pb)
 	if err != nil {
-		return "", fmt.Errorf("error authenticating: %w", err)
+		return "", err
 	}
+	return ref.URI, nil
+}
 
-	url := fmt.Sprintf("%s/xrpc/com.atproto.repo.createRecord", c.server)
-	pr, err := pb.buildFor(c.server, c.httpClient)
+// createPost publishes the post and returns a strong ref to it.
+func (c *Client) createPost(ctx context.Context, pb *PostBuilder) (*StrongRef, error) {
+	if err := pb.Validate(); err != nil {
+		return nil, err
+	}
+	token, err := c.ensureSession(ctx)
 	if err != nil {
-		return "", fmt.Errorf("error building post request: %w", err)
+		return nil, fmt.Errorf("error authenticating: %w", err)
+	}
+	did := c.sessionDid()
+	if did == "" {
+		return nil, errors.New("error creating post: session has no DID")
 	}
-	pr.Repo = c.handle // Set the repo to the user's handle
 
-	err = c.embedImagesInPost(pb, pr)
+	pr, err := c.buildPost(ctx, token, pb)
 	if err != nil {
-		return "", fmt.Errorf("error embedding images in post: %w", err)
+		return nil, err
 	}
 
-	jsonBody, err := json.Marshal(pr)
-	if err != nil {
-		return "", fmt.Errorf("error marshaling request body: %w", err)
+	rkey := newTID()
+	gates := pb.gateWrites(fmt.Sprintf("at://%s/%s/%s", did, pr.Collection, rkey), rkey)
+	if len(gates) == 0 {
+		resp, err := c.createRecord(ctx, token, pr.Repo, pr.Collection, rkey, pr.Record)
+		if err != nil {
+			return nil, fmt.Errorf("error creating post: %w", err)
+		}
+		return &StrongRef{URI: resp.Uri, CID: resp.Cid}, nil
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+
+	// Create the post and its gates together, so the post is never visible
+	// without them
+	writes := append([]recordWrite{newCreateWrite(pr.Collection, rkey, pr.Record)}, gates...)
+	results, err := c.createRecords(ctx, token, did, writes)
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
+		return nil, fmt.Errorf("error creating post: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.accessToken)
-	resp, err := c.httpClient.Do(req)
+	return &StrongRef{URI: results[0].Uri, CID: results[0].Cid}, nil
+}
+
+// buildPost builds the post record, resolving its reply and quote refs and
+// uploading its media.
+func (c *Client) buildPost(ctx context.Context, token string, pb *PostBuilder) (*postRequest, error) {
+	pr, err := pb.buildFor(ctx, c.server, c.httpClient)
 	if err != nil {
-		return "", fmt.Errorf("error making request: %w", err)
+		return nil, fmt.Errorf("error building post request: %w", err)
 	}
-	defer func() {
-		err = errors.Join(err, resp.Body.Close())
-	}()
+	pr.Repo = c.handle // Set the repo to the user's handle
 
-	b, err := io.ReadAll(resp.Body) // Read the response body to ensure we consume it
+	err = c.resolveReply(ctx, token, pb, pr)
 	if err != nil {
-		return "", fmt.Errorf("error reading response body: %w", err)
+		return nil, fmt.Errorf("error resolving reply: %w", err)
 	}
-	var postResponse struct {
-		// Success
-		Uri string `json:"uri"`
-		Cid string `json:"cid"`
 
-		// Error
-		Error   string `json:"error"`
-		Message string `json:"message"`
-	}
-	if err := json.Unmarshal(b, &postResponse); err != nil {
-		return "", fmt.Errorf("error unmarshaling response: %w", err)
-	}
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("post failed with status code: %d (%s) error: %s message: %s", resp.StatusCode, resp.Status, postResponse.Error, postResponse.Message)
+	err = c.resolveQuote(ctx, token, pb)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving quoted post: %w", err)
 	}
-	return postResponse.Uri, nil
-}
 
-// auth logs in to the server using the provided handle and password.
-func (c *Client) auth() error {
-	url := fmt.Sprintf("%s/xrpc/com.atproto.server.createSession", c.server)
-	requestBody := map[string]string{
-		"identifier": c.handle,
-		"password":   c.password,
-	}
-	jsonBody, err := json.Marshal(requestBody)
+	err = c.embedImagesInPost(ctx, pb, pr, token)
 	if err != nil {
-		return fmt.Errorf("error marshaling request body: %w", err)
+		return nil, fmt.Errorf("error embedding images in post: %w", err)
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+
+	err = c.embedVideoInPost(ctx, pb, pr, token)
 	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+		return nil, fmt.Errorf("error embedding video in post: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := c.httpClient.Do(req)
+
+	err = c.embedLinkCardInPost(ctx, pb, pr, token)
 	if err != nil {
-		return fmt.Errorf("error making request: %w", err)
+		return nil, fmt.Errorf("error embedding link card in post: %w", err)
 	}
-	defer func() {
-		err = errors.Join(err, resp.Body.Close())
-	}()
+	embedQuoteInPost(pb, pr)
+	return pr, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("login failed with status code: %d", resp.StatusCode)
-	}
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("error reading response body: %w", err)
+// auth logs in to the server using the provided handle and password and
+// replaces the current session.
+func (c *Client) auth(ctx context.Context) error {
+	requestBody := map[string]string{
+		"identifier": c.handle,
+		"password":   c.password,
 	}
-	// var sessionResponse SessionResponse
 	var sessionResponse struct {
 		AccessJwt  string `json:"accessJwt"`
 		RefreshJwt string `json:"refreshJwt"`
+		Did        string `json:"did"`
 	}
-	if err := json.Unmarshal(b, &sessionResponse); err != nil {
-		return fmt.Errorf("error unmarshaling response: %w", err)
+	err := c.xrpc(ctx, &xrpcRequest{
+		method: "POST",
+		nsid:   "com.atproto.server.createSession",
+		body:   requestBody,
+	}, &sessionResponse)
+	if err != nil {
+		return fmt.Errorf("error logging in: %w", err)
 	}
-	c.accessToken = sessionResponse.AccessJwt
-	return err
+	c.session = newSession(sessionResponse.AccessJwt, sessionResponse.RefreshJwt, sessionResponse.Did)
+	return nil
 }
 
 // embedImagesInPost uploads images to the server and embeds them in the post record.
 // It scales images down to ensure they are under 1MiB in size.
-func (c *Client) embedImagesInPost(pb *PostBuilder, pr *postRequest) error {
+func (c *Client) embedImagesInPost(ctx context.Context, pb *PostBuilder, pr *postRequest, token string) error {
 	if len(pb.images) == 0 {
 		return nil
 	}
-	uploadUrl := fmt.Sprintf("%s/xrpc/com.atproto.repo.uploadBlob", c.server)
-
 	// First, upload the images and save their references
-	embeddedImages := make([]*image, 0, len(pb.images))
+	embeddedImages := make([]*EmbedImage, 0, len(pb.images))
 	for _, img := range pb.images {
-		// If the image file size is too large, scale it until it is under 1MiB
-		data := make([]byte, len(img.Bytes))
-		copy(data, img.Bytes)
-		scaleFactor := 1.0
-		var err error
-		for len(data) > 1_000_000 {
-			scaleFactor *= 0.9 // Reduce size by 10% each iteration
-			data, err = scaleImage(img.Bytes, scaleFactor)
-			if err != nil {
-				return fmt.Errorf("error scaling image: %w", err)
-			}
-		}
-
-		// Figure out the image type and dimensions
-		mimetype := http.DetectContentType(data)
-		config, _, err := goimage.DecodeConfig(bytes.NewReader(data))
+		data, mimetype, config, err := prepareImage(img.Bytes)
 		if err != nil {
-			log.Printf("Error decoding image config: %v", err)
+			log.Printf("Error preparing %s: %v", img, err)
 			continue
 		}
-
-		// Upload the image to the server
-		req, err := http.NewRequest("POST", uploadUrl, bytes.NewBuffer(data))
+		blob, err := c.uploadBlob(ctx, token, data, mimetype)
 		if err != nil {
-			return fmt.Errorf("error creating upload request: %w", err)
-		}
-		req.Header.Set("Content-Type", mimetype)
-		req.Header.Set("Authorization", "Bearer "+c.accessToken)
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return fmt.Errorf("error uploading image: %w", err)
-		}
-		defer func() {
-			err = errors.Join(err, resp.Body.Close())
-		}()
-		b, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("error reading upload response body: %w", err)
-		}
-		var uploadResponse struct {
-			// Success
-			Blob imageEmbed `json:"blob"`
-
-			// Error
-			Error   string `json:"error"`
-			Message string `json:"message"`
-		}
-		if err := json.Unmarshal(b, &uploadResponse); err != nil {
-			return fmt.Errorf("error unmarshaling upload response: %w", err)
-		}
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("upload of %s failed with status code: %d (%s) error: %s message: %s", img, resp.StatusCode, resp.Status, uploadResponse.Error, uploadResponse.Message)
+			return fmt.Errorf("error uploading %s: %w", img, err)
 		}
 
 		// Create the JSON object for this image
-		image := &image{
-			Image: &uploadResponse.Blob,
+		image := &EmbedImage{
+			Image: blob,
 			Alt:   img.Alt,
-			AspectRatio: &struct {
-				Width  int `json:"width"`
-				Height int `json:"height"`
-			}{
+			AspectRatio: &AspectRatio{
 				Width:  config.Width,
 				Height: config.Height,
 			},
@@ -497,10 +483,7 @@ func (c *Client) embedImagesInPost(pb *PostBuilder, pr *postRequest) error {
 	}
 
 	// Then, embed the image references in the post record
-	pr.Record.Embed = &struct {
-		Type   string   "json:\"$type\""
-		Images []*image "json:\"images,omitempty\""
-	}{
+	pr.Record.Embed = &Embed{
 		Type:   "app.bsky.embed.images",
 		Images: embeddedImages,
 	}
@@ -508,6 +491,48 @@ func (c *Client) embedImagesInPost(pb *PostBuilder, pr *postRequest) error {
 	return nil
 }
 
+// prepareImage scales an image down until it is under 1MiB in size, and
+// returns the scaled image with its MIME type and dimensions.
+func prepareImage(img []byte) ([]byte, string, goimage.Config, error) {
+	data := make([]byte, len(img))
+	copy(data, img)
+	scaleFactor := 1.0
+	var err error
+	for len(data) > 1_000_000 {
+		scaleFactor *= 0.9 // Reduce size by 10% each iteration
+		data, err = scaleImage(img, scaleFactor)
+		if err != nil {
+			return nil, "", goimage.Config{}, fmt.Errorf("error scaling image: %w", err)
+		}
+	}
+
+	// Figure out the image type and dimensions
+	mimetype := http.DetectContentType(data)
+	config, _, err := goimage.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", goimage.Config{}, fmt.Errorf("error decoding image config: %w", err)
+	}
+	return data, mimetype, config, nil
+}
+
+// uploadBlob uploads data to the server and returns a reference to the blob.
+func (c *Client) uploadBlob(ctx context.Context, token string, data []byte, mimetype string) (*Blob, error) {
+	var uploadResponse struct {
+		Blob Blob `json:"blob"`
+	}
+	err := c.xrpc(ctx, &xrpcRequest{
+		method:      "POST",
+		nsid:        "com.atproto.repo.uploadBlob",
+		rawBody:     data,
+		contentType: mimetype,
+		token:       token,
+	}, &uploadResponse)
+	if err != nil {
+		return nil, err
+	}
+	return &uploadResponse.Blob, nil
+}
+
 // scaleImage scales an image to the specified scale factor.
 func scaleImage(data []byte, scale float64) ([]byte, error) {
 	src, format, err := goimage.Decode(bytes.NewReader(data))
@@ -2,6 +2,8 @@ package ltbsky
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -36,7 +38,7 @@ func TestAuth(t *testing.T) {
 	if err != nil {
 		t.Fatalf("wanted no error, got %v", err)
 	}
-	err = client.auth()
+	err = client.auth(context.Background())
 	if err != nil {
 		t.Fatalf("wanted no error, got %v", err)
 	}
@@ -59,6 +61,23 @@ func TestPost(t *testing.T) {
 	}
 }
 
+func TestPostContextCanceled(t *testing.T) {
+	server := newMockServer()
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test.handle", "test.password")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.PostContext(ctx, NewPostBuilder("Hello, world!"))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("wanted context.Canceled, got %v", err)
+	}
+}
+
 func TestPostWithLinks(t *testing.T) {
 	server := newMockServer()
 	defer server.Close()
@@ -180,7 +199,7 @@ func TestPostBuilderAddImage(t *testing.T) {
 	if len(pb.images) != 2 || pb.images[1].Path != path {
 		t.Errorf("wanted image paths ['%s'], got %v", path, pb.images[1].Path)
 	}
-	_, err := pb.buildFor("https://bsky.social", &http.Client{})
+	_, err := pb.buildFor(context.Background(), "https://bsky.social", &http.Client{})
 	if err != nil {
 		t.Fatalf("wanted no error, got %v", err)
 	}
@@ -350,11 +369,15 @@ func TestParseLinks(t *testing.T) {
 	}
 }
 
+type mockContextKey struct{}
+
 type mockHTTPClient struct {
 	responses map[string]string
+	requests  []*http.Request
 }
 
 func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	m.requests = append(m.requests, req)
 	if req.URL.Path == "/xrpc/com.atproto.identity.resolveHandle" {
 		handle := req.URL.Query().Get("handle")
 		if did, ok := m.responses[handle]; ok {
@@ -456,7 +479,14 @@ func TestParseMentions(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &mockHTTPClient{responses: tt.mockResponses}
 			pb := NewPostBuilder(tt.content)
-			pb.parseMentions("", c)
+			ctx := context.WithValue(context.Background(), mockContextKey{}, tt.name)
+			pb.parseMentions(ctx, "", c)
+
+			for _, req := range c.requests {
+				if req.Context().Value(mockContextKey{}) != tt.name {
+					t.Errorf("wanted request for %s to use the caller's context", req.URL)
+				}
+			}
 
 			if len(pb.facets) != len(tt.expectedFacets) {
 				t.Errorf("wanted %d facets, got %d", len(tt.expectedFacets), len(pb.facets))
@@ -501,7 +531,7 @@ func newMockServer() *httptest.Server {
 		switch r.URL.Path {
 		case "/xrpc/com.atproto.server.createSession":
 			w.WriteHeader(http.StatusOK)
-			_, err := w.Write([]byte(`{"accessJwt": "test.token"}`))
+			_, err := w.Write([]byte(`{"accessJwt": "test.token", "did": "did:plc:test"}`))
 			if err != nil {
 				return
 			}
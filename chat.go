@@ -0,0 +1,294 @@
+package ltbsky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ChatService is the service that handles Bluesky direct messages. Chat
+// requests are sent to the user's server, which forwards them to it.
+const ChatService = "did:web:api.bsky.chat#bsky_chat"
+
+// Limits enforced by Chat.SendMessage.
+const (
+	// MaxMessageGraphemes is the maximum length of a message's text.
+	MaxMessageGraphemes = 1000
+	// MaxMessageBytes is the maximum length of a message's text in UTF-8
+	// bytes.
+	MaxMessageBytes = 10_000
+)
+
+// ErrInvalidMessage is wrapped by errors from SendMessage for messages that
+// break Bluesky's limits.
+var ErrInvalidMessage = errors.New("invalid message")
+
+// Types of chat log entries.
+const (
+	LogBeginConvo    = "chat.bsky.convo.defs#logBeginConvo"
+	LogLeaveConvo    = "chat.bsky.convo.defs#logLeaveConvo"
+	LogCreateMessage = "chat.bsky.convo.defs#logCreateMessage"
+	LogDeleteMessage = "chat.bsky.convo.defs#logDeleteMessage"
+)
+
+// A Chat sends and receives direct messages for the logged-in user. The app
+// password must be allowed to access direct messages.
+type Chat struct {
+	client *Client
+}
+
+// Chat returns a Chat that uses the client's session.
+func (c *Client) Chat() *Chat {
+	return &Chat{client: c}
+}
+
+// A Convo is a conversation between the logged-in user and other accounts.
+type Convo struct {
+	ID          string       `json:"id"`
+	Rev         string       `json:"rev"`
+	Members     []Actor      `json:"members"`
+	LastMessage *ChatMessage `json:"lastMessage,omitempty"`
+	Muted       bool         `json:"muted"`
+	UnreadCount int          `json:"unreadCount"`
+}
+
+// A ChatMessage is a message in a conversation. A deleted message has Type
+// "chat.bsky.convo.defs#deletedMessageView" and no text.
+type ChatMessage struct {
+	Type   string  `json:"$type,omitempty"`
+	ID     string  `json:"id"`
+	Rev    string  `json:"rev"`
+	Text   string  `json:"text,omitempty"`
+	Facets []Facet `json:"facets,omitempty"`
+	Sender struct {
+		Did string `json:"did"`
+	} `json:"sender"`
+	SentAt time.Time `json:"sentAt"`
+}
+
+// A ChatLogEntry is a change to one of the logged-in user's conversations.
+// Message is set for LogCreateMessage and LogDeleteMessage entries.
+type ChatLogEntry struct {
+	Type    string       `json:"$type"`
+	Rev     string       `json:"rev"`
+	ConvoID string       `json:"convoId"`
+	Message *ChatMessage `json:"message,omitempty"`
+}
+
+// messageInput is a message to send, as a chat.bsky.convo.defs#messageInput.
+type messageInput struct {
+	Text   string  `json:"text"`
+	Facets []Facet `json:"facets,omitempty"`
+}
+
+// ListConvosOptions controls the pages returned by ListConvos.
+type ListConvosOptions struct {
+	// Limit is the maximum number of conversations per page, up to 100. Zero
+	// means the server's default.
+	Limit int
+	// Cursor is the cursor returned with the previous page, or empty for the
+	// first page.
+	Cursor string
+}
+
+// GetConvoForMembers returns the conversation between the logged-in user and
+// the accounts with the given handles or DIDs, starting one if needed.
+func (ch *Chat) GetConvoForMembers(ctx context.Context, members ...string) (*Convo, error) {
+	c := ch.client
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error authenticating: %w", err)
+	}
+	params := url.Values{}
+	for _, member := range members {
+		did, err := c.resolveActor(ctx, token, member)
+		if err != nil {
+			return nil, err
+		}
+		params.Add("members", did)
+	}
+	var resp struct {
+		Convo Convo `json:"convo"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "chat.bsky.convo.getConvoForMembers",
+		params: params,
+		token:  token,
+		proxy:  ChatService,
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching conversation: %w", err)
+	}
+	return &resp.Convo, nil
+}
+
+// SendMessage sends text to the conversation with the given ID. Links,
+// mentions, and hashtags in the text are detected the same way as in posts.
+//
+// Since the server cannot tell a repeated message from a new one, a failed
+// message is only retried after a rate limit.
+func (ch *Chat) SendMessage(ctx context.Context, convoID, text string) (*ChatMessage, error) {
+	c := ch.client
+	if n := graphemeCount(text); n > MaxMessageGraphemes {
+		return nil, fmt.Errorf("%w: text is %d graphemes, over the limit of %d", ErrInvalidMessage, n, MaxMessageGraphemes)
+	}
+	if n := len(text); n > MaxMessageBytes {
+		return nil, fmt.Errorf("%w: text is %d bytes, over the limit of %d", ErrInvalidMessage, n, MaxMessageBytes)
+	}
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error authenticating: %w", err)
+	}
+	pr, err := NewPostBuilder(text).buildFor(ctx, c.server, c.httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("error building message: %w", err)
+	}
+	requestBody := map[string]any{
+		"convoId": convoID,
+		"message": &messageInput{Text: text, Facets: pr.Record.Facets},
+	}
+	var msg ChatMessage
+	err = c.xrpc(ctx, &xrpcRequest{
+		method:      "POST",
+		nsid:        "chat.bsky.convo.sendMessage",
+		body:        requestBody,
+		token:       token,
+		proxy:       ChatService,
+		unsafeRetry: true,
+	}, &msg)
+	if err != nil {
+		return nil, fmt.Errorf("error sending message: %w", err)
+	}
+	return &msg, nil
+}
+
+// ListConvos returns a page of the logged-in user's conversations, most
+// recently active first, and the cursor of the next page. The cursor is empty
+// after the last page.
+func (ch *Chat) ListConvos(ctx context.Context, opts ListConvosOptions) ([]Convo, string, error) {
+	c := ch.client
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("error authenticating: %w", err)
+	}
+	params := url.Values{}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		params.Set("cursor", opts.Cursor)
+	}
+	var resp struct {
+		Convos []Convo `json:"convos"`
+		Cursor string  `json:"cursor"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "chat.bsky.convo.listConvos",
+		params: params,
+		token:  token,
+		proxy:  ChatService,
+	}, &resp)
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing conversations: %w", err)
+	}
+	return resp.Convos, resp.Cursor, nil
+}
+
+// IterConvos returns an iterator over the logged-in user's conversations,
+// starting at opts.Cursor and fetching pages as needed. If a page cannot be
+// fetched, the iterator yields the error and stops.
+func (ch *Chat) IterConvos(ctx context.Context, opts ListConvosOptions) iter.Seq2[Convo, error] {
+	return paginate(opts.Cursor, func(cursor string) ([]Convo, string, error) {
+		opts.Cursor = cursor
+		return ch.ListConvos(ctx, opts)
+	})
+}
+
+// GetLog returns the changes to the logged-in user's conversations after
+// cursor, oldest first, and the cursor to pass to the next call. Pass an
+// empty cursor to get the latest changes.
+func (ch *Chat) GetLog(ctx context.Context, cursor string) ([]ChatLogEntry, string, error) {
+	c := ch.client
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("error authenticating: %w", err)
+	}
+	params := url.Values{}
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+	var resp struct {
+		Logs   []ChatLogEntry `json:"logs"`
+		Cursor string         `json:"cursor"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "chat.bsky.convo.getLog",
+		params: params,
+		token:  token,
+		proxy:  ChatService,
+	}, &resp)
+	if err != nil {
+		return nil, "", fmt.Errorf("error fetching chat log: %w", err)
+	}
+	if resp.Cursor == "" {
+		resp.Cursor = cursor
+	}
+	return resp.Logs, resp.Cursor, nil
+}
+
+// A MessageHandler handles a message received in a conversation.
+type MessageHandler func(ctx context.Context, convoID string, msg *ChatMessage) error
+
+// WatchMessages polls the chat log every interval, starting after cursor, and
+// passes each new message from another account to handle, until ctx is done.
+// It then returns ctx.Err(). An interval of zero means DefaultWatchInterval.
+// Pass the cursor from an earlier GetLog call to skip older messages. Errors
+// are logged; a message whose handler fails is handled again on the next poll.
+func (ch *Chat) WatchMessages(ctx context.Context, cursor string, interval time.Duration, handle MessageHandler) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	for {
+		next, err := ch.pollMessages(ctx, cursor, handle)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error polling messages: %v", err)
+		}
+		cursor = next
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// pollMessages handles the messages in the chat log after cursor. It returns
+// the cursor after the last handled entry.
+func (ch *Chat) pollMessages(ctx context.Context, cursor string, handle MessageHandler) (string, error) {
+	entries, next, err := ch.GetLog(ctx, cursor)
+	if err != nil {
+		return cursor, err
+	}
+	self := ch.client.sessionDid()
+	for _, e := range entries {
+		if e.Type == LogCreateMessage && e.Message != nil && e.Message.Sender.Did != self {
+			if err := handle(ctx, e.ConvoID, e.Message); err != nil {
+				return cursor, fmt.Errorf("error handling message %s: %w", e.Message.ID, err)
+			}
+		}
+		if e.Rev != "" {
+			cursor = e.Rev
+		}
+	}
+	return next, nil
+}
@@ -0,0 +1,284 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// checkProxy checks that r was proxied to the chat service, and fails the
+// test otherwise.
+func checkProxy(t *testing.T, r *http.Request) {
+	t.Helper()
+	if got := r.Header.Get("atproto-proxy"); got != ChatService {
+		t.Errorf("wanted atproto-proxy %s, got '%s'", ChatService, got)
+	}
+}
+
+func TestGetConvoForMembers(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("chat.bsky.convo.getConvoForMembers", func(w http.ResponseWriter, r *http.Request) {
+		checkProxy(t, r)
+		members := r.URL.Query()["members"]
+		if fmt.Sprint(members) != "[did:plc:oncall-example-com did:plc:bob]" {
+			t.Errorf("wanted resolved member DIDs, got %v", members)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"convo": map[string]any{
+			"id":      "convo1",
+			"rev":     "2",
+			"members": []map[string]string{{"did": "did:plc:test", "handle": "test.handle"}, {"did": "did:plc:bob", "handle": "bob.bsky.social"}},
+		}})
+	})
+	client := pds.newClient(t)
+
+	convo, err := client.Chat().GetConvoForMembers(context.Background(), "@oncall.example.com", "did:plc:bob")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if convo.ID != "convo1" || len(convo.Members) != 2 {
+		t.Errorf("wanted convo1 with 2 members, got %+v", convo)
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	pds := newFakePDS(t)
+	var body struct {
+		ConvoID string `json:"convoId"`
+		Message struct {
+			Text   string  `json:"text"`
+			Facets []Facet `json:"facets"`
+		} `json:"message"`
+	}
+	pds.on("chat.bsky.convo.sendMessage", func(w http.ResponseWriter, r *http.Request) {
+		checkProxy(t, r)
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("wanted no error, got %v", err)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"id":     "msg1",
+			"rev":    "3",
+			"text":   body.Message.Text,
+			"sender": map[string]string{"did": "did:plc:test"},
+			"sentAt": "2024-01-02T03:04:05.000Z",
+		})
+	})
+	client := pds.newClient(t)
+
+	msg, err := client.Chat().SendMessage(context.Background(), "convo1", "Disk full on db1 https://status.example.com #incident")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if msg.ID != "msg1" || msg.SentAt.IsZero() {
+		t.Errorf("wanted message msg1 with a sent time, got %+v", msg)
+	}
+	if body.ConvoID != "convo1" {
+		t.Errorf("wanted convoId convo1, got '%s'", body.ConvoID)
+	}
+	if len(body.Message.Facets) != 2 {
+		t.Fatalf("wanted 2 facets, got %+v", body.Message.Facets)
+	}
+	if f := body.Message.Facets[0]; f.Features[0].Uri != "https://status.example.com" {
+		t.Errorf("wanted a link to https://status.example.com, got %+v", f)
+	}
+	if f := body.Message.Facets[1]; f.Features[0].Tag != "incident" {
+		t.Errorf("wanted tag incident, got %+v", f)
+	}
+}
+
+func TestSendMessageWithoutFacets(t *testing.T) {
+	pds := newFakePDS(t)
+	var body struct {
+		Message map[string]json.RawMessage `json:"message"`
+	}
+	pds.on("chat.bsky.convo.sendMessage", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("wanted no error, got %v", err)
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"id": "msg1"})
+	})
+	client := pds.newClient(t)
+
+	if _, err := client.Chat().SendMessage(context.Background(), "convo1", "Hello"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if string(body.Message["text"]) != `"Hello"` {
+		t.Errorf("wanted text Hello, got %s", body.Message["text"])
+	}
+	if facets, ok := body.Message["facets"]; ok {
+		t.Errorf("wanted no facets key, got %s", facets)
+	}
+}
+
+func TestSendMessageTooLong(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	_, err := client.Chat().SendMessage(context.Background(), "convo1", strings.Repeat("a", MaxMessageGraphemes+1))
+	if !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("wanted ErrInvalidMessage, got %v", err)
+	}
+	if n := pds.count("chat.bsky.convo.sendMessage"); n != 0 {
+		t.Errorf("wanted no requests, got %d", n)
+	}
+}
+
+func TestSendMessageRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		errName  string
+		attempts int
+		wantErr  bool
+	}{
+		{"Server error", http.StatusServiceUnavailable, "", 1, true},
+		{"Rate limit", http.StatusTooManyRequests, "RateLimitExceeded", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pds := newFakePDS(t)
+			pds.on("chat.bsky.convo.sendMessage", func(w http.ResponseWriter, r *http.Request) {
+				if pds.count("chat.bsky.convo.sendMessage") == 1 {
+					writeError(w, tt.status, tt.errName, "")
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]string{"id": "msg1"})
+			})
+			client := pds.newClient(t, WithRetryPolicy(testRetryPolicy))
+
+			_, err := client.Chat().SendMessage(context.Background(), "convo1", "Hello")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wanted error %v, got %v", tt.wantErr, err)
+			}
+			if n := pds.count("chat.bsky.convo.sendMessage"); n != tt.attempts {
+				t.Errorf("wanted %d attempts, got %d", tt.attempts, n)
+			}
+		})
+	}
+}
+
+func TestIterConvos(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("chat.bsky.convo.listConvos", func(w http.ResponseWriter, r *http.Request) {
+		checkProxy(t, r)
+		resp := map[string]any{"convos": []map[string]any{{"id": "convo2", "unreadCount": 1}}}
+		if r.URL.Query().Get("cursor") == "" {
+			resp = map[string]any{"convos": []map[string]any{{"id": "convo1"}}, "cursor": "c1"}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+	client := pds.newClient(t)
+
+	var ids []string
+	for convo, err := range client.Chat().IterConvos(context.Background(), ListConvosOptions{Limit: 1}) {
+		if err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+		ids = append(ids, convo.ID)
+	}
+	if fmt.Sprint(ids) != "[convo1 convo2]" {
+		t.Errorf("wanted [convo1 convo2], got %v", ids)
+	}
+}
+
+// fakeChatLog serves chat.bsky.convo.getLog from a list of entries, using
+// their revs as cursors.
+type fakeChatLog struct {
+	mu      sync.Mutex
+	entries []map[string]any
+	cursors []string
+}
+
+func (l *fakeChatLog) add(rev, sender, text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, map[string]any{
+		"$type":   LogCreateMessage,
+		"rev":     rev,
+		"convoId": "convo1",
+		"message": map[string]any{"id": "msg" + rev, "rev": rev, "text": text, "sender": map[string]string{"did": sender}},
+	})
+}
+
+func (l *fakeChatLog) serve(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cursor := r.URL.Query().Get("cursor")
+	l.cursors = append(l.cursors, cursor)
+	var logs []map[string]any
+	for _, e := range l.entries {
+		if e["rev"].(string) > cursor {
+			logs = append(logs, e)
+		}
+	}
+	resp := map[string]any{"logs": logs}
+	if len(logs) > 0 {
+		resp["cursor"] = logs[len(logs)-1]["rev"]
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func TestGetLog(t *testing.T) {
+	pds := newFakePDS(t)
+	chatLog := &fakeChatLog{}
+	chatLog.add("1", "did:plc:bob", "Hi")
+	chatLog.add("2", "did:plc:test", "Hello")
+	pds.on("chat.bsky.convo.getLog", chatLog.serve)
+	client := pds.newClient(t)
+
+	entries, cursor, err := client.Chat().GetLog(context.Background(), "")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(entries) != 2 || cursor != "2" || entries[0].Message.Text != "Hi" {
+		t.Fatalf("wanted 2 entries and cursor 2, got %+v and cursor '%s'", entries, cursor)
+	}
+	entries, cursor, err = client.Chat().GetLog(context.Background(), cursor)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(entries) != 0 || cursor != "2" {
+		t.Errorf("wanted no entries and cursor 2, got %+v and cursor '%s'", entries, cursor)
+	}
+}
+
+func TestWatchMessages(t *testing.T) {
+	pds := newFakePDS(t)
+	chatLog := &fakeChatLog{}
+	chatLog.add("1", "did:plc:bob", "Old")
+	chatLog.add("2", "did:plc:bob", "Ack")
+	chatLog.add("3", "did:plc:test", "Mine")
+	chatLog.add("4", "did:plc:bob", "Resolved")
+	pds.on("chat.bsky.convo.getLog", chatLog.serve)
+	client := pds.newClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []string
+	failed := false
+	handle := func(ctx context.Context, convoID string, msg *ChatMessage) error {
+		if msg.Text == "Resolved" && !failed {
+			failed = true
+			return errors.New("handler failed")
+		}
+		got = append(got, convoID+":"+msg.Text)
+		if msg.Text == "Resolved" {
+			cancel()
+		}
+		return nil
+	}
+	err := client.Chat().WatchMessages(ctx, "1", time.Millisecond, handle)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("wanted context.Canceled, got %v", err)
+	}
+	if fmt.Sprint(got) != "[convo1:Ack convo1:Resolved]" {
+		t.Errorf("wanted [convo1:Ack convo1:Resolved], got %v", got)
+	}
+	if fmt.Sprint(chatLog.cursors[:2]) != "[1 3]" {
+		t.Errorf("wanted cursors 1 and then 3, got %v", chatLog.cursors)
+	}
+}
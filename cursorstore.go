@@ -0,0 +1,103 @@
+package ltbsky
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// A CursorStore persists the position of a stream, such as a
+// NotificationWatcher's last seen notification, so it can resume after the
+// process restarts. Cursors are opaque strings, keyed by the name of the
+// stream.
+type CursorStore interface {
+	// Load returns the stored cursor for key, or "" if there is none.
+	Load(key string) (string, error)
+	// Save stores the cursor for key, replacing any previous cursor.
+	Save(key, cursor string) error
+}
+
+// FileCursorStore is a CursorStore backed by a JSON file. The file is replaced
+// atomically on every write.
+type FileCursorStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileCursorStore creates a FileCursorStore that reads and writes the file
+// at path. The file is created on the first save.
+func NewFileCursorStore(path string) *FileCursorStore {
+	return &FileCursorStore{path: path}
+}
+
+// Load returns the stored cursor for key, or "" if there is none.
+func (f *FileCursorStore) Load(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cursors, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	return cursors[key], nil
+}
+
+// Save stores the cursor for key, replacing any previous cursor.
+func (f *FileCursorStore) Save(key, cursor string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cursors, err := f.read()
+	if err != nil {
+		return err
+	}
+	cursors[key] = cursor
+	b, err := json.MarshalIndent(cursors, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling cursors: %w", err)
+	}
+	return writeFileAtomic(f.path, b, 0o600)
+}
+
+func (f *FileCursorStore) read() (map[string]string, error) {
+	cursors := make(map[string]string)
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cursors, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading cursor file: %w", err)
+	}
+	if err := json.Unmarshal(b, &cursors); err != nil {
+		return nil, fmt.Errorf("error unmarshaling cursor file: %w", err)
+	}
+	return cursors, nil
+}
+
+// MemoryCursorStore is a CursorStore that keeps cursors in memory. It is
+// mostly useful for tests.
+type MemoryCursorStore struct {
+	mu      sync.Mutex
+	cursors map[string]string
+}
+
+// NewMemoryCursorStore creates an empty MemoryCursorStore.
+func NewMemoryCursorStore() *MemoryCursorStore {
+	return &MemoryCursorStore{cursors: make(map[string]string)}
+}
+
+// Load returns the stored cursor for key, or "" if there is none.
+func (m *MemoryCursorStore) Load(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cursors[key], nil
+}
+
+// Save stores the cursor for key, replacing any previous cursor.
+func (m *MemoryCursorStore) Save(key, cursor string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cursors[key] = cursor
+	return nil
+}
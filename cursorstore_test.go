@@ -0,0 +1,66 @@
+package ltbsky
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileCursorStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cursors.json")
+	store := NewFileCursorStore(path)
+
+	cursor, err := store.Load("notifications")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if cursor != "" {
+		t.Errorf("wanted no cursor, got '%s'", cursor)
+	}
+
+	if err := store.Save("notifications", "abc"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if err := store.Save("jetstream", "123"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("wanted file permissions 0600, got %o", perm)
+	}
+
+	reloaded := NewFileCursorStore(path)
+	for key, want := range map[string]string{"notifications": "abc", "jetstream": "123"} {
+		cursor, err := reloaded.Load(key)
+		if err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+		if cursor != want {
+			t.Errorf("wanted cursor '%s' for %s, got '%s'", want, key, cursor)
+		}
+	}
+	matches, err := filepath.Glob(path + ".tmp-*")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("wanted no temporary files, got %v", matches)
+	}
+}
+
+func TestMemoryCursorStore(t *testing.T) {
+	store := NewMemoryCursorStore()
+	if err := store.Save("notifications", "abc"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	cursor, err := store.Load("notifications")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if cursor != "abc" {
+		t.Errorf("wanted cursor 'abc', got '%s'", cursor)
+	}
+}
@@ -0,0 +1,38 @@
+package ltbsky
+
+import (
+	"context"
+	"fmt"
+)
+
+// DeletePost deletes the post at atURI. Deleting a post that does not exist
+// is not an error.
+func (c *Client) DeletePost(ctx context.Context, atURI string) error {
+	u, err := parseATURI(atURI)
+	if err != nil {
+		return err
+	}
+	if u.collection != "app.bsky.feed.post" {
+		return fmt.Errorf("%s is not a post", atURI)
+	}
+	return c.DeleteRecord(ctx, atURI, "")
+}
+
+// DeleteRecord deletes the record at atURI, which may be in any collection.
+// If swapCID is not empty, the record is only deleted if its CID still
+// matches; otherwise the returned error wraps ErrInvalidSwap.
+func (c *Client) DeleteRecord(ctx context.Context, atURI string, swapCID string) error {
+	u, err := parseATURI(atURI)
+	if err != nil {
+		return err
+	}
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+	err = c.deleteRecord(ctx, token, u.repo, u.collection, u.rkey, swapCID)
+	if err != nil {
+		return fmt.Errorf("error deleting %s: %w", atURI, err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package ltbsky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeletePost(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	uri, err := client.Post(NewPostBuilder("Oops"))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	if err := client.DeletePost(context.Background(), uri); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if record := pds.record(uri); record != nil {
+		t.Errorf("wanted record to be deleted, got %v", record)
+	}
+}
+
+func TestDeletePostNotAPost(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	err := client.DeletePost(context.Background(), "at://did:plc:test/app.bsky.feed.like/3klike")
+	if err == nil {
+		t.Fatal("wanted error, got nil")
+	}
+	if n := pds.count("com.atproto.repo.deleteRecord"); n != 0 {
+		t.Errorf("wanted no deleteRecord calls, got %d", n)
+	}
+}
+
+func TestDeleteRecordSwap(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := "at://did:plc:test/app.bsky.feed.like/3klike"
+	cid := pds.seed(uri, map[string]any{"$type": "app.bsky.feed.like"})
+	client := pds.newClient(t)
+
+	err := client.DeleteRecord(context.Background(), uri, "bafyoutdated")
+	if !errors.Is(err, ErrInvalidSwap) {
+		t.Fatalf("wanted ErrInvalidSwap, got %v", err)
+	}
+	if pds.record(uri) == nil {
+		t.Fatal("wanted record to be kept, got nil")
+	}
+
+	if err := client.DeleteRecord(context.Background(), uri, cid); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if record := pds.record(uri); record != nil {
+		t.Errorf("wanted record to be deleted, got %v", record)
+	}
+}
+
+func TestDeleteRecordRetryAfterLostResponse(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := "at://did:plc:test/app.bsky.feed.post/3kpost"
+	cid := pds.seed(uri, map[string]any{"$type": "app.bsky.feed.post", "text": "Oops"})
+	// Delete the record, but fail as if the response was lost
+	pds.on("com.atproto.repo.deleteRecord", func(w http.ResponseWriter, r *http.Request) {
+		pds.serveDefault(httptest.NewRecorder(), r)
+		writeError(w, http.StatusServiceUnavailable, "", "")
+	})
+	client := pds.newClient(t, WithRetryPolicy(testRetryPolicy))
+
+	if err := client.DeleteRecord(context.Background(), uri, cid); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.deleteRecord"); n != 1 {
+		t.Errorf("wanted 1 deleteRecord call, got %d", n)
+	}
+}
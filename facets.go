@@ -0,0 +1,259 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The facet detector follows the rich-text rules of the official atproto
+// client (see detectFacets in @atproto/api):
+//
+//   - Mentions and links must start the text or follow whitespace or "(".
+//   - Tags must start the text or follow whitespace.
+//   - A link runs to the next whitespace, minus one trailing punctuation mark
+//     and an unbalanced ")".
+//   - A tag runs to the next whitespace or invisible separator, minus any
+//     trailing punctuation. It must contain something other than digits and
+//     punctuation, and be at most 64 UTF-16 code units long.
+//
+// Unlike the official client, links without an http:// or https:// scheme
+// are not detected, since that needs an up-to-date list of top-level domains.
+
+// Facet kinds found by detectFacets.
+const (
+	facetLink    = "link"
+	facetMention = "mention"
+	facetTag     = "tag"
+)
+
+// maxTagLength is the maximum length of a tag, in UTF-16 code units.
+const maxTagLength = 64
+
+// handleRegex matches valid handles, based on:
+// https://atproto.com/specs/handle#handle-identifier-syntax
+var handleRegex = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// detectedFacet is a link, mention, or tag found in a post's text. start and
+// end are byte offsets into the text; value is the link's URI, the mention's
+// handle, or the tag without its '#'.
+type detectedFacet struct {
+	kind  string
+	start int
+	end   int
+	value string
+}
+
+// detectFacets returns the links, mentions, and tags in text, in order. The
+// scan resumes after the end of each facet, so facets never overlap.
+func detectFacets(text string) []detectedFacet {
+	var facets []detectedFacet
+	prev := rune(-1) // the rune before i, or -1 at the start of the text
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		var f *detectedFacet
+		switch {
+		case r == '@' && (prev == -1 || isFacetSpace(prev) || prev == '('):
+			f = detectMention(text, i)
+		case (r == 'h' || r == 'H') && (prev == -1 || isFacetSpace(prev) || prev == '('):
+			f = detectLink(text, i)
+		case (r == '#' || r == '＃') && (prev == -1 || isFacetSpace(prev)):
+			f = detectTag(text, i, size)
+		}
+		if f != nil {
+			facets = append(facets, *f)
+			prev, _ = utf8.DecodeLastRuneInString(text[:f.end])
+			i = f.end
+			continue
+		}
+		prev = r
+		i += size
+	}
+	return facets
+}
+
+// detectMention returns the mention starting with the '@' at text[start], or
+// nil if there is no valid handle there.
+func detectMention(text string, start int) *detectedFacet {
+	end := start + 1
+	for end < len(text) && isHandleByte(text[end]) {
+		end++
+	}
+	// Back off to a word boundary, as the trailing \b of the official regex
+	// does, so trailing dots and hyphens are not part of the handle
+	for end > start+1 && isWordByte(text[end-1]) == (end < len(text) && isWordByte(text[end])) {
+		end--
+	}
+	handle := text[start+1 : end]
+	if len(handle) > 253 || !handleRegex.MatchString(handle) {
+		return nil
+	}
+	return &detectedFacet{kind: facetMention, start: start, end: end, value: handle}
+}
+
+// detectLink returns the link starting at text[start], or nil if there is
+// no http:// or https:// link there.
+func detectLink(text string, start int) *detectedFacet {
+	rest := text[start:]
+	lower := strings.ToLower(rest[:min(len(rest), 8)])
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return nil
+	}
+	end := start + strings.IndexFunc(rest, isFacetSpace)
+	if end < start {
+		end = len(text)
+	}
+	uri := text[start:end]
+	if strings.ContainsAny(uri[len(uri)-1:], ".,;:!?") {
+		uri = uri[:len(uri)-1]
+	}
+	if strings.HasSuffix(uri, ")") && !strings.Contains(uri, "(") {
+		uri = uri[:len(uri)-1]
+	}
+	_, after, _ := strings.Cut(uri, "://")
+	if after == "" {
+		return nil
+	}
+	return &detectedFacet{kind: facetLink, start: start, end: start + len(uri), value: uri}
+}
+
+// detectTag returns the tag starting with the '#' or '＃' of length hashLen
+// at text[start], or nil if there is no valid tag there.
+func detectTag(text string, start, hashLen int) *detectedFacet {
+	rest := text[start+hashLen:]
+	n := strings.IndexFunc(rest, func(r rune) bool {
+		return isFacetSpace(r) || isTagSeparator(r)
+	})
+	if n < 0 {
+		n = len(rest)
+	}
+	tag := rest[:n]
+	if strings.HasPrefix(tag, "\ufe0f") {
+		return nil
+	}
+	tag = strings.TrimRightFunc(tag, unicode.IsPunct)
+	if tag == "" || len(utf16.Encode([]rune(tag))) > maxTagLength {
+		return nil
+	}
+	if strings.IndexFunc(tag, func(r rune) bool { return !unicode.IsPunct(r) && (r < '0' || r > '9') }) < 0 {
+		// Tags made of only digits and punctuation, like "#1", are not tags
+		return nil
+	}
+	return &detectedFacet{kind: facetTag, start: start, end: start + hashLen + len(tag), value: tag}
+}
+
+// isFacetSpace reports whether r is whitespace, as matched by \s in
+// JavaScript regular expressions.
+func isFacetSpace(r rune) bool {
+	return unicode.IsSpace(r) || r == '\ufeff'
+}
+
+// isTagSeparator reports whether r is an invisible character that ends a tag.
+func isTagSeparator(r rune) bool {
+	switch r {
+	case '\u00ad', '\u2060', '\u200a', '\u200b', '\u200c', '\u200d', '\u20e2':
+		return true
+	}
+	return false
+}
+
+func isHandleByte(b byte) bool {
+	return b == '.' || b == '-' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+func (pb *PostBuilder) parseLinks() {
+	for _, d := range detectFacets(pb.content) {
+		if d.kind != facetLink {
+			continue
+		}
+		f := &Facet{
+			Features: []FacetFeature{
+				{Type: "app.bsky.richtext.facet#link", Uri: d.value},
+			},
+		}
+		f.Index.ByteStart = d.start
+		f.Index.ByteEnd = d.end
+		pb.facets = append(pb.facets, f)
+	}
+}
+
+type HttpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+func (pb *PostBuilder) parseMentions(ctx context.Context, server string, c HttpClient) {
+	for _, d := range detectFacets(pb.content) {
+		if d.kind != facetMention {
+			continue
+		}
+		handle := d.value
+		url := fmt.Sprintf("%s/xrpc/com.atproto.identity.resolveHandle?handle=%s", server, handle)
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			log.Printf("Error creating request for handle %s: %v", handle, err)
+			continue
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			log.Printf("Error making request for handle %s: %v", handle, err)
+			continue
+		}
+		defer func() {
+			err = errors.Join(err, resp.Body.Close())
+		}()
+
+		if resp.StatusCode != http.StatusOK {
+			log.Printf("Failed to resolve handle %s with status code: %d", handle, resp.StatusCode)
+			continue
+		}
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			log.Printf("Error reading response body for handle %s: %v", handle, err)
+			continue
+		}
+		var resolveResponse struct {
+			Did string `json:"did"`
+		}
+		if err := json.Unmarshal(b, &resolveResponse); err != nil {
+			log.Printf("Error unmarshaling response for handle %s: %v", handle, err)
+			continue
+		}
+		f := &Facet{
+			Features: []FacetFeature{
+				{Type: "app.bsky.richtext.facet#mention", Did: resolveResponse.Did},
+			},
+		}
+		f.Index.ByteStart = d.start
+		f.Index.ByteEnd = d.end
+		pb.facets = append(pb.facets, f)
+	}
+}
+
+func (pb *PostBuilder) parseTags() {
+	for _, d := range detectFacets(pb.content) {
+		if d.kind != facetTag {
+			continue
+		}
+		f := &Facet{
+			Features: []FacetFeature{
+				{Type: "app.bsky.richtext.facet#tag", Tag: d.value},
+			},
+		}
+		f.Index.ByteStart = d.start
+		f.Index.ByteEnd = d.end
+		pb.facets = append(pb.facets, f)
+	}
+}
@@ -0,0 +1,193 @@
+package ltbsky
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// facetCorpus is a conformance corpus for the facet detector. Each facet is
+// described by its kind and the exact text it covers.
+var facetCorpus = []struct {
+	name string
+	text string
+	want []struct{ kind, text string }
+}{
+	{
+		name: "Plain text",
+		text: "Hello, world!",
+	},
+	{
+		name: "Tag at start",
+		text: "#golang is fun",
+		want: []struct{ kind, text string }{{facetTag, "#golang"}},
+	},
+	{
+		name: "Tags with trailing punctuation",
+		text: "Tags: #golang, #bsky.",
+		want: []struct{ kind, text string }{{facetTag, "#golang"}, {facetTag, "#bsky"}},
+	},
+	{
+		name: "Numeric tags",
+		text: "#1 #2024 #123abc",
+		want: []struct{ kind, text string }{{facetTag, "#123abc"}},
+	},
+	{
+		name: "Emoji tag",
+		text: "Party #🎉 time",
+		want: []struct{ kind, text string }{{facetTag, "#🎉"}},
+	},
+	{
+		name: "CJK tags",
+		text: "日本語 #タグ。 ＃全角",
+		want: []struct{ kind, text string }{{facetTag, "#タグ"}, {facetTag, "＃全角"}},
+	},
+	{
+		name: "Tag after CJK punctuation",
+		text: "日本語、#タグ",
+	},
+	{
+		name: "Tag after ideographic space",
+		text: "こんにちは　#挨拶",
+		want: []struct{ kind, text string }{{facetTag, "#挨拶"}},
+	},
+	{
+		name: "Tag after newline",
+		text: "First line\n#second",
+		want: []struct{ kind, text string }{{facetTag, "#second"}},
+	},
+	{
+		name: "Tag with combining characters",
+		text: "café #naïve",
+		want: []struct{ kind, text string }{{facetTag, "#naïve"}},
+	},
+	{
+		name: "Tag ended by zero-width space",
+		text: "#tag​after",
+		want: []struct{ kind, text string }{{facetTag, "#tag"}},
+	},
+	{
+		name: "Keycap emoji",
+		text: "#️⃣ is not a tag",
+	},
+	{
+		name: "Tag at the length limit",
+		text: "#" + strings.Repeat("a", 64) + " #" + strings.Repeat("b", 65),
+		want: []struct{ kind, text string }{{facetTag, "#" + strings.Repeat("a", 64)}},
+	},
+	{
+		name: "Mention after emoji",
+		text: "🎉 @golang.org 🎉",
+		want: []struct{ kind, text string }{{facetMention, "@golang.org"}},
+	},
+	{
+		name: "Mention with trailing punctuation",
+		text: "Thanks @alice.bsky.social. And @golang.org's team!",
+		want: []struct{ kind, text string }{{facetMention, "@alice.bsky.social"}, {facetMention, "@golang.org"}},
+	},
+	{
+		name: "Mention in parentheses",
+		text: "(@alice.bsky.social)",
+		want: []struct{ kind, text string }{{facetMention, "@alice.bsky.social"}},
+	},
+	{
+		name: "Email address",
+		text: "Write to me@example.com",
+	},
+	{
+		name: "Handle without a dot",
+		text: "Hi @alice",
+	},
+	{
+		name: "Link with trailing period",
+		text: "See https://go.dev.",
+		want: []struct{ kind, text string }{{facetLink, "https://go.dev"}},
+	},
+	{
+		name: "Link in parentheses",
+		text: "Go (https://go.dev) and https://en.wikipedia.org/wiki/Go_(programming_language)",
+		want: []struct{ kind, text string }{
+			{facetLink, "https://go.dev"},
+			{facetLink, "https://en.wikipedia.org/wiki/Go_(programming_language)"},
+		},
+	},
+	{
+		name: "Mention and tag inside a link",
+		text: "https://example.com/@golang.org/#fragment",
+		want: []struct{ kind, text string }{{facetLink, "https://example.com/@golang.org/#fragment"}},
+	},
+	{
+		name: "Link after CJK text",
+		text: "詳細は https://例え.jp/パス をご覧ください",
+		want: []struct{ kind, text string }{{facetLink, "https://例え.jp/パス"}},
+	},
+	{
+		name: "Link glued to text",
+		text: "xhttps://go.dev",
+	},
+	{
+		name: "Everything with emoji",
+		text: "👨‍👩‍👧 Family news from @golang.org: https://go.dev/blog #家族 #golang🐹",
+		want: []struct{ kind, text string }{
+			{facetMention, "@golang.org"},
+			{facetLink, "https://go.dev/blog"},
+			{facetTag, "#家族"},
+			{facetTag, "#golang🐹"},
+		},
+	},
+}
+
+func TestDetectFacets(t *testing.T) {
+	for _, tt := range facetCorpus {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectFacets(tt.text)
+			if len(got) != len(tt.want) {
+				t.Fatalf("wanted %d facets, got %d: %+v", len(tt.want), len(got), got)
+			}
+			for i, want := range tt.want {
+				f := got[i]
+				if i > 0 && f.start < got[i-1].end {
+					t.Errorf("facet %d: wanted start at or after %d, got %d", i, got[i-1].end, f.start)
+				}
+				if f.kind != want.kind {
+					t.Errorf("facet %d: wanted kind %s, got %s", i, want.kind, f.kind)
+				}
+				token := tt.text[f.start:f.end]
+				if token != want.text {
+					t.Errorf("facet %d: wanted text '%s', got '%s'", i, want.text, token)
+				}
+				if !utf8.ValidString(token) {
+					t.Errorf("facet %d: wanted valid UTF-8, got %q", i, token)
+				}
+				// The value must match the text the facet covers
+				var wantValue string
+				switch f.kind {
+				case facetLink:
+					wantValue = token
+				case facetMention:
+					wantValue = strings.TrimPrefix(token, "@")
+				case facetTag:
+					wantValue = strings.TrimPrefix(strings.TrimPrefix(token, "#"), "＃")
+				}
+				if f.value != wantValue {
+					t.Errorf("facet %d: wanted value '%s', got '%s'", i, wantValue, f.value)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildForDoesNotDuplicateFacets(t *testing.T) {
+	pb := NewPostBuilder("https://go.dev #golang")
+	c := &mockHTTPClient{}
+	for range 2 {
+		pr, err := pb.buildFor(context.Background(), "", c)
+		if err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+		if len(pr.Record.Facets) != 2 {
+			t.Errorf("wanted 2 facets, got %d", len(pr.Record.Facets))
+		}
+	}
+}
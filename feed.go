@@ -0,0 +1,239 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// An Actor is a Bluesky account as shown to the logged-in user. Which fields
+// are set depends on the method that returned it.
+type Actor struct {
+	Did         string       `json:"did"`
+	Handle      string       `json:"handle"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Avatar      string       `json:"avatar,omitempty"`
+	IndexedAt   time.Time    `json:"indexedAt"`
+	Viewer      *ActorViewer `json:"viewer,omitempty"`
+	CreatedAt   time.Time    `json:"createdAt"`
+}
+
+// ActorViewer is the logged-in user's relationship with an account. Following
+// and FollowedBy hold the URIs of the follow records, if any.
+type ActorViewer struct {
+	Muted      bool   `json:"muted,omitempty"`
+	BlockedBy  bool   `json:"blockedBy,omitempty"`
+	Blocking   string `json:"blocking,omitempty"`
+	Following  string `json:"following,omitempty"`
+	FollowedBy string `json:"followedBy,omitempty"`
+}
+
+// A PostView is a post as shown to the logged-in user, with its author and
+// counts.
+type PostView struct {
+	URI    string     `json:"uri"`
+	CID    string     `json:"cid"`
+	Author Actor      `json:"author"`
+	Record PostRecord `json:"record"`
+	// Embed is the embed as the server presents it, with image URLs and the
+	// content of quoted posts. Record.Embed holds the embed as it is stored.
+	Embed       json.RawMessage `json:"embed,omitempty"`
+	ReplyCount  int             `json:"replyCount"`
+	RepostCount int             `json:"repostCount"`
+	LikeCount   int             `json:"likeCount"`
+	QuoteCount  int             `json:"quoteCount"`
+	IndexedAt   time.Time       `json:"indexedAt"`
+	Viewer      *PostViewer     `json:"viewer,omitempty"`
+}
+
+// PostViewer is the logged-in user's relationship with a post. Like and
+// Repost hold the URIs of the like and repost records, if any.
+type PostViewer struct {
+	Like              string `json:"like,omitempty"`
+	Repost            string `json:"repost,omitempty"`
+	ThreadMuted       bool   `json:"threadMuted,omitempty"`
+	ReplyDisabled     bool   `json:"replyDisabled,omitempty"`
+	EmbeddingDisabled bool   `json:"embeddingDisabled,omitempty"`
+}
+
+// A ThreadView is a post in a thread, with its parent and replies. A post
+// that was deleted or is hidden from the user has only its URI set, and
+// NotFound or Blocked.
+type ThreadView struct {
+	Type     string        `json:"$type"`
+	Post     *PostView     `json:"post,omitempty"`
+	Parent   *ThreadView   `json:"parent,omitempty"`
+	Replies  []*ThreadView `json:"replies,omitempty"`
+	URI      string        `json:"uri,omitempty"`
+	NotFound bool          `json:"notFound,omitempty"`
+	Blocked  bool          `json:"blocked,omitempty"`
+}
+
+// A FeedItem is a post in a feed. Reply is set if the post is a reply, and
+// Reason if it is in the feed because it was reposted.
+type FeedItem struct {
+	Post   PostView    `json:"post"`
+	Reply  *FeedReply  `json:"reply,omitempty"`
+	Reason *FeedReason `json:"reason,omitempty"`
+}
+
+// FeedReply holds the root and parent posts of a reply in a feed. A post that
+// was deleted or is hidden from the user has only its URI set.
+type FeedReply struct {
+	Root   PostView `json:"root"`
+	Parent PostView `json:"parent"`
+}
+
+// FeedReason is why a post is in a feed. Type is
+// "app.bsky.feed.defs#reasonRepost", with the reposting account in By, or
+// "app.bsky.feed.defs#reasonPin".
+type FeedReason struct {
+	Type      string    `json:"$type"`
+	By        *Actor    `json:"by,omitempty"`
+	IndexedAt time.Time `json:"indexedAt"`
+}
+
+// AuthorFeedFilter selects the posts returned by GetAuthorFeed.
+type AuthorFeedFilter string
+
+// Filters for GetAuthorFeed.
+const (
+	PostsWithReplies      AuthorFeedFilter = "posts_with_replies"
+	PostsNoReplies        AuthorFeedFilter = "posts_no_replies"
+	PostsWithMedia        AuthorFeedFilter = "posts_with_media"
+	PostsAndAuthorThreads AuthorFeedFilter = "posts_and_author_threads"
+	PostsWithVideo        AuthorFeedFilter = "posts_with_video"
+)
+
+// AuthorFeedOptions controls which posts GetAuthorFeed returns.
+type AuthorFeedOptions struct {
+	// Filter selects the kinds of posts. Zero means PostsWithReplies.
+	Filter AuthorFeedFilter
+	// Limit is the maximum number of posts per page, up to 100. Zero means
+	// the server's default.
+	Limit int
+	// Cursor is the cursor returned with the previous page, or empty for the
+	// first page.
+	Cursor string
+}
+
+// maxGetPosts is the most posts app.bsky.feed.getPosts returns per request.
+const maxGetPosts = 25
+
+// GetPost returns the post at atURI. If there is no such post, the returned
+// error wraps ErrRecordNotFound.
+func (c *Client) GetPost(ctx context.Context, atURI string) (*PostView, error) {
+	posts, err := c.GetPosts(ctx, atURI)
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, fmt.Errorf("error fetching post %s: %w", atURI, ErrRecordNotFound)
+	}
+	return &posts[0], nil
+}
+
+// GetPosts returns the posts at the given AT-URIs. Posts that do not exist
+// are left out.
+func (c *Client) GetPosts(ctx context.Context, atURIs ...string) ([]PostView, error) {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error authenticating: %w", err)
+	}
+	return c.getPosts(ctx, token, atURIs)
+}
+
+func (c *Client) getPosts(ctx context.Context, token string, atURIs []string) ([]PostView, error) {
+	var posts []PostView
+	for start := 0; start < len(atURIs); start += maxGetPosts {
+		var resp struct {
+			Posts []PostView `json:"posts"`
+		}
+		err := c.xrpc(ctx, &xrpcRequest{
+			method: "GET",
+			nsid:   "app.bsky.feed.getPosts",
+			params: url.Values{"uris": atURIs[start:min(start+maxGetPosts, len(atURIs))]},
+			token:  token,
+		}, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching posts: %w", err)
+		}
+		posts = append(posts, resp.Posts...)
+	}
+	return posts, nil
+}
+
+// GetPostThread returns the thread around the post at atURI: up to depth
+// levels of replies below it, and up to parentHeight posts above it.
+func (c *Client) GetPostThread(ctx context.Context, atURI string, depth, parentHeight int) (*ThreadView, error) {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error authenticating: %w", err)
+	}
+	var resp struct {
+		Thread ThreadView `json:"thread"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "app.bsky.feed.getPostThread",
+		params: url.Values{
+			"uri":          {atURI},
+			"depth":        {strconv.Itoa(depth)},
+			"parentHeight": {strconv.Itoa(parentHeight)},
+		},
+		token: token,
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching thread %s: %w", atURI, err)
+	}
+	return &resp.Thread, nil
+}
+
+// GetAuthorFeed returns a page of posts and reposts by the account with the
+// given handle or DID, newest first, and the cursor of the next page. The
+// cursor is empty after the last page.
+func (c *Client) GetAuthorFeed(ctx context.Context, actor string, opts AuthorFeedOptions) ([]FeedItem, string, error) {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("error authenticating: %w", err)
+	}
+	params := url.Values{"actor": {actor}}
+	if opts.Filter != "" {
+		params.Set("filter", string(opts.Filter))
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		params.Set("cursor", opts.Cursor)
+	}
+	var resp struct {
+		Feed   []FeedItem `json:"feed"`
+		Cursor string     `json:"cursor"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "app.bsky.feed.getAuthorFeed",
+		params: params,
+		token:  token,
+	}, &resp)
+	if err != nil {
+		return nil, "", fmt.Errorf("error fetching feed of %s: %w", actor, err)
+	}
+	return resp.Feed, resp.Cursor, nil
+}
+
+// IterAuthorFeed returns an iterator over the author's feed, starting at
+// opts.Cursor and fetching pages as needed. If a page cannot be fetched, the
+// iterator yields the error and stops.
+func (c *Client) IterAuthorFeed(ctx context.Context, actor string, opts AuthorFeedOptions) iter.Seq2[FeedItem, error] {
+	return paginate(opts.Cursor, func(cursor string) ([]FeedItem, string, error) {
+		opts.Cursor = cursor
+		return c.GetAuthorFeed(ctx, actor, opts)
+	})
+}
@@ -0,0 +1,253 @@
+package ltbsky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// postView returns a post view as the server would send it.
+func postView(uri, text string) map[string]any {
+	return map[string]any{
+		"uri":    uri,
+		"cid":    "bafy" + text,
+		"author": map[string]any{"did": "did:plc:alice", "handle": "alice.bsky.social", "displayName": "Alice"},
+		"record": map[string]any{
+			"$type":     "app.bsky.feed.post",
+			"text":      text,
+			"createdAt": "2024-01-02T03:04:05Z",
+			"facets": []map[string]any{{
+				"index":    map[string]int{"byteStart": 0, "byteEnd": 7},
+				"features": []map[string]string{{"$type": "app.bsky.richtext.facet#tag", "tag": "golang"}},
+			}},
+			"embed": map[string]any{
+				"$type":  "app.bsky.embed.recordWithMedia",
+				"record": map[string]any{"record": map[string]string{"uri": "at://did:plc:bob/app.bsky.feed.post/3kquoted", "cid": "bafyquoted"}},
+				"media":  map[string]any{"$type": "app.bsky.embed.images", "images": []map[string]any{{"alt": "A gopher"}}},
+			},
+		},
+		"embed":     map[string]any{"$type": "app.bsky.embed.recordWithMedia#view"},
+		"likeCount": 3,
+		"indexedAt": "2024-01-02T03:04:06.123Z",
+		"viewer":    map[string]string{"like": "at://did:plc:test/app.bsky.feed.like/3klike"},
+	}
+}
+
+func TestGetPost(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := "at://did:plc:alice/app.bsky.feed.post/3kpost"
+	pds.on("app.bsky.feed.getPosts", func(w http.ResponseWriter, r *http.Request) {
+		var posts []map[string]any
+		for _, u := range r.URL.Query()["uris"] {
+			if u == uri {
+				posts = append(posts, postView(uri, "#golang rocks"))
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"posts": posts})
+	})
+	client := pds.newClient(t)
+
+	post, err := client.GetPost(context.Background(), uri)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if post.URI != uri || post.Author.Handle != "alice.bsky.social" || post.LikeCount != 3 {
+		t.Errorf("wanted post by alice.bsky.social with 3 likes, got %+v", post)
+	}
+	if post.Record.Text != "#golang rocks" {
+		t.Errorf("wanted text '#golang rocks', got '%s'", post.Record.Text)
+	}
+	if len(post.Record.Facets) != 1 || post.Record.Facets[0].Features[0].Tag != "golang" {
+		t.Errorf("wanted a golang tag facet, got %+v", post.Record.Facets)
+	}
+	embed := post.Record.Embed
+	if embed == nil || embed.Record == nil || embed.Record.URI != "at://did:plc:bob/app.bsky.feed.post/3kquoted" {
+		t.Fatalf("wanted a quoted post, got %+v", embed)
+	}
+	if embed.Media == nil || len(embed.Media.Images) != 1 || embed.Media.Images[0].Alt != "A gopher" {
+		t.Errorf("wanted an image with alt text 'A gopher', got %+v", embed.Media)
+	}
+	if post.Viewer == nil || post.Viewer.Like == "" {
+		t.Errorf("wanted viewer like, got %+v", post.Viewer)
+	}
+	if post.IndexedAt.IsZero() {
+		t.Error("wanted indexedAt, got zero time")
+	}
+
+	_, err = client.GetPost(context.Background(), "at://did:plc:alice/app.bsky.feed.post/3kmissing")
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("wanted ErrRecordNotFound, got %v", err)
+	}
+}
+
+func TestGetPostsBatches(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("app.bsky.feed.getPosts", func(w http.ResponseWriter, r *http.Request) {
+		uris := r.URL.Query()["uris"]
+		if len(uris) > maxGetPosts {
+			t.Errorf("wanted at most %d uris, got %d", maxGetPosts, len(uris))
+		}
+		var posts []map[string]any
+		for _, u := range uris {
+			posts = append(posts, postView(u, "Post"))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"posts": posts})
+	})
+	client := pds.newClient(t)
+
+	var uris []string
+	for i := range 30 {
+		uris = append(uris, fmt.Sprintf("at://did:plc:alice/app.bsky.feed.post/%d", i))
+	}
+	posts, err := client.GetPosts(context.Background(), uris...)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(posts) != 30 {
+		t.Errorf("wanted 30 posts, got %d", len(posts))
+	}
+	if n := pds.count("app.bsky.feed.getPosts"); n != 2 {
+		t.Errorf("wanted 2 requests, got %d", n)
+	}
+}
+
+func TestGetPostThread(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := "at://did:plc:alice/app.bsky.feed.post/3kpost"
+	pds.on("app.bsky.feed.getPostThread", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("uri") != uri || q.Get("depth") != "2" || q.Get("parentHeight") != "0" {
+			t.Errorf("wanted uri %s, depth 2, and parentHeight 0, got %v", uri, q)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"thread": map[string]any{
+			"$type": "app.bsky.feed.defs#threadViewPost",
+			"post":  postView(uri, "Root"),
+			"replies": []map[string]any{
+				{"$type": "app.bsky.feed.defs#threadViewPost", "post": postView(uri+"1", "Reply")},
+				{"$type": "app.bsky.feed.defs#notFoundPost", "uri": uri + "2", "notFound": true},
+			},
+		}})
+	})
+	client := pds.newClient(t)
+
+	thread, err := client.GetPostThread(context.Background(), uri, 2, 0)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if thread.Post == nil || thread.Post.Record.Text != "Root" {
+		t.Fatalf("wanted root post, got %+v", thread)
+	}
+	if len(thread.Replies) != 2 {
+		t.Fatalf("wanted 2 replies, got %d", len(thread.Replies))
+	}
+	if thread.Replies[0].Post.Record.Text != "Reply" {
+		t.Errorf("wanted first reply 'Reply', got %+v", thread.Replies[0].Post)
+	}
+	if !thread.Replies[1].NotFound || thread.Replies[1].Post != nil {
+		t.Errorf("wanted second reply to be not found, got %+v", thread.Replies[1])
+	}
+}
+
+// handleAuthorFeed serves an author feed of n posts in pages of the
+// requested size.
+func handleAuthorFeed(t *testing.T, pds *fakePDS, n int) {
+	pds.on("app.bsky.feed.getAuthorFeed", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("actor") != "alice.bsky.social" || q.Get("filter") != string(PostsNoReplies) {
+			t.Errorf("wanted actor alice.bsky.social and filter %s, got %v", PostsNoReplies, q)
+		}
+		start, _ := strconv.Atoi(q.Get("cursor"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		var feed []map[string]any
+		for i := start; i < min(start+limit, n); i++ {
+			feed = append(feed, map[string]any{"post": postView(fmt.Sprintf("at://did:plc:alice/app.bsky.feed.post/%d", i), strconv.Itoa(i))})
+		}
+		resp := map[string]any{"feed": feed}
+		if start+limit < n {
+			resp["cursor"] = strconv.Itoa(start + limit)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+func TestGetAuthorFeed(t *testing.T) {
+	pds := newFakePDS(t)
+	handleAuthorFeed(t, pds, 5)
+	client := pds.newClient(t)
+
+	opts := AuthorFeedOptions{Filter: PostsNoReplies, Limit: 3}
+	feed, cursor, err := client.GetAuthorFeed(context.Background(), "alice.bsky.social", opts)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(feed) != 3 || cursor != "3" {
+		t.Fatalf("wanted 3 posts and cursor 3, got %d posts and cursor '%s'", len(feed), cursor)
+	}
+	opts.Cursor = cursor
+	feed, cursor, err = client.GetAuthorFeed(context.Background(), "alice.bsky.social", opts)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(feed) != 2 || cursor != "" {
+		t.Errorf("wanted 2 posts and no cursor, got %d posts and cursor '%s'", len(feed), cursor)
+	}
+}
+
+func TestIterAuthorFeed(t *testing.T) {
+	pds := newFakePDS(t)
+	handleAuthorFeed(t, pds, 7)
+	client := pds.newClient(t)
+
+	var texts []string
+	for item, err := range client.IterAuthorFeed(context.Background(), "alice.bsky.social", AuthorFeedOptions{Filter: PostsNoReplies, Limit: 3}) {
+		if err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+		texts = append(texts, item.Post.Record.Text)
+	}
+	if fmt.Sprint(texts) != "[0 1 2 3 4 5 6]" {
+		t.Errorf("wanted posts 0 to 6, got %v", texts)
+	}
+	if n := pds.count("app.bsky.feed.getAuthorFeed"); n != 3 {
+		t.Errorf("wanted 3 requests, got %d", n)
+	}
+}
+
+func TestIterAuthorFeedStopsEarly(t *testing.T) {
+	pds := newFakePDS(t)
+	handleAuthorFeed(t, pds, 7)
+	client := pds.newClient(t)
+
+	n := 0
+	for _, err := range client.IterAuthorFeed(context.Background(), "alice.bsky.social", AuthorFeedOptions{Filter: PostsNoReplies, Limit: 3}) {
+		if err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	if n := pds.count("app.bsky.feed.getAuthorFeed"); n != 1 {
+		t.Errorf("wanted 1 request, got %d", n)
+	}
+}
+
+func TestIterAuthorFeedError(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("app.bsky.feed.getAuthorFeed", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusBadRequest, "AccountTakedown", "Account has been taken down")
+	})
+	client := pds.newClient(t)
+
+	var errs []error
+	for _, err := range client.IterAuthorFeed(context.Background(), "alice.bsky.social", AuthorFeedOptions{}) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrAccountTakedown) {
+		t.Errorf("wanted a single ErrAccountTakedown, got %v", errs)
+	}
+}
@@ -0,0 +1,88 @@
+package ltbsky
+
+import (
+	"time"
+)
+
+// MaxReplyRules is the maximum number of rules passed to RestrictReplies.
+const MaxReplyRules = 5
+
+// A ReplyRule allows a group of accounts to reply to a post that restricts
+// replies.
+type ReplyRule struct {
+	Type string `json:"$type"`
+	// List is the URI of the list whose members may reply, for list rules.
+	List string `json:"list,omitempty"`
+}
+
+// Rules for RestrictReplies.
+var (
+	// MentionedCanReply allows the accounts mentioned in the post to reply.
+	MentionedCanReply = ReplyRule{Type: "app.bsky.feed.threadgate#mentionRule"}
+	// FollowingCanReply allows the accounts the author follows to reply.
+	FollowingCanReply = ReplyRule{Type: "app.bsky.feed.threadgate#followingRule"}
+	// FollowersCanReply allows the author's followers to reply.
+	FollowersCanReply = ReplyRule{Type: "app.bsky.feed.threadgate#followerRule"}
+)
+
+// ListMembersCanReply returns a rule that allows the members of the list at
+// listURI to reply.
+func ListMembersCanReply(listURI string) ReplyRule {
+	return ReplyRule{Type: "app.bsky.feed.threadgate#listRule", List: listURI}
+}
+
+// RestrictReplies allows only the accounts matched by one of the rules to
+// reply to the post and the rest of its thread. With no rules, nobody can
+// reply. Only the first post of a thread can restrict replies.
+func (pb *PostBuilder) RestrictReplies(rules ...ReplyRule) *PostBuilder {
+	pb.replyRules = append([]ReplyRule{}, rules...)
+	return pb
+}
+
+// DisableQuotes stops other accounts from quoting the post.
+func (pb *PostBuilder) DisableQuotes() *PostBuilder {
+	pb.disableQuotes = true
+	return pb
+}
+
+// threadgateRecord is an app.bsky.feed.threadgate record. It restricts the
+// replies to the thread of the post it shares its record key with.
+type threadgateRecord struct {
+	Type      string      `json:"$type"`
+	Post      string      `json:"post"`
+	Allow     []ReplyRule `json:"allow"`
+	CreatedAt string      `json:"createdAt"`
+}
+
+// postgateRecord is an app.bsky.feed.postgate record. It restricts how the
+// post it shares its record key with can be embedded.
+type postgateRecord struct {
+	Type           string           `json:"$type"`
+	Post           string           `json:"post"`
+	EmbeddingRules []map[string]any `json:"embeddingRules"`
+	CreatedAt      string           `json:"createdAt"`
+}
+
+// gateWrites returns the writes that create the post's threadgate and
+// postgate, if it has any, for a post at postURI with the given record key.
+func (pb *PostBuilder) gateWrites(postURI, rkey string) []recordWrite {
+	createdAt := time.Now().UTC().Format(time.RFC3339)
+	var writes []recordWrite
+	if pb.replyRules != nil {
+		writes = append(writes, newCreateWrite("app.bsky.feed.threadgate", rkey, &threadgateRecord{
+			Type:      "app.bsky.feed.threadgate",
+			Post:      postURI,
+			Allow:     pb.replyRules,
+			CreatedAt: createdAt,
+		}))
+	}
+	if pb.disableQuotes {
+		writes = append(writes, newCreateWrite("app.bsky.feed.postgate", rkey, &postgateRecord{
+			Type:           "app.bsky.feed.postgate",
+			Post:           postURI,
+			EmbeddingRules: []map[string]any{{"$type": "app.bsky.feed.postgate#disableRule"}},
+			CreatedAt:      createdAt,
+		}))
+	}
+	return writes
+}
@@ -0,0 +1,145 @@
+package ltbsky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPostWithGates(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	listURI := "at://did:plc:test/app.bsky.graph.list/3klist"
+
+	pb := NewPostBuilder("Only friends can reply").
+		RestrictReplies(FollowingCanReply, ListMembersCanReply(listURI)).
+		DisableQuotes()
+	ref, err := client.PostContext(context.Background(), pb)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.applyWrites"); n != 1 {
+		t.Errorf("wanted 1 applyWrites call, got %d", n)
+	}
+	if n := pds.count("com.atproto.repo.createRecord"); n != 0 {
+		t.Errorf("wanted no createRecord calls, got %d", n)
+	}
+	if post := pds.record(ref); post["text"] != "Only friends can reply" {
+		t.Errorf("wanted the post, got %v", post)
+	}
+
+	rkey := ref[strings.LastIndex(ref, "/")+1:]
+	threadgate := pds.record("at://did:plc:test/app.bsky.feed.threadgate/" + rkey)
+	if threadgate == nil || threadgate["post"] != ref {
+		t.Fatalf("wanted a threadgate for %s, got %v", ref, threadgate)
+	}
+	want := "[map[$type:app.bsky.feed.threadgate#followingRule] map[$type:app.bsky.feed.threadgate#listRule list:" + listURI + "]]"
+	if got := fmt.Sprint(threadgate["allow"]); got != want {
+		t.Errorf("wanted allow %s, got %s", want, got)
+	}
+	postgate := pds.record("at://did:plc:test/app.bsky.feed.postgate/" + rkey)
+	if postgate == nil || postgate["post"] != ref {
+		t.Fatalf("wanted a postgate for %s, got %v", ref, postgate)
+	}
+	if got := fmt.Sprint(postgate["embeddingRules"]); got != "[map[$type:app.bsky.feed.postgate#disableRule]]" {
+		t.Errorf("wanted a disable rule, got %s", got)
+	}
+}
+
+func TestPostNobodyCanReply(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	ref, err := client.PostContext(context.Background(), NewPostBuilder("No replies").RestrictReplies())
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	rkey := ref[strings.LastIndex(ref, "/")+1:]
+	threadgate := pds.record("at://did:plc:test/app.bsky.feed.threadgate/" + rkey)
+	if allow, ok := threadgate["allow"].([]any); !ok || len(allow) != 0 {
+		t.Errorf("wanted an empty allow list, got %v", threadgate["allow"])
+	}
+	if uris := pds.uris("app.bsky.feed.postgate"); len(uris) != 0 {
+		t.Errorf("wanted no postgate, got %v", uris)
+	}
+}
+
+func TestPostWithGatesWithoutSessionDid(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("com.atproto.server.createSession", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"accessJwt": "test.token", "refreshJwt": "test.refresh"})
+	})
+	client := pds.newClient(t)
+
+	_, err := client.PostContext(context.Background(), NewPostBuilder("No replies").RestrictReplies())
+	if err == nil {
+		t.Fatal("wanted an error, got nil")
+	}
+	if n := pds.count("com.atproto.repo.applyWrites"); n != 0 {
+		t.Errorf("wanted no applyWrites calls, got %d", n)
+	}
+	if uris := pds.uris("app.bsky.feed.threadgate"); len(uris) != 0 {
+		t.Errorf("wanted no threadgate, got %v", uris)
+	}
+}
+
+func TestPostWithGatesRetryAfterLostResponse(t *testing.T) {
+	pds := newFakePDS(t)
+	// Apply the writes, but fail as if the response was lost
+	pds.on("com.atproto.repo.applyWrites", func(w http.ResponseWriter, r *http.Request) {
+		pds.serveDefault(httptest.NewRecorder(), r)
+		writeError(w, http.StatusServiceUnavailable, "", "")
+	})
+	client := pds.newClient(t, WithRetryPolicy(testRetryPolicy))
+
+	ref, err := client.PostContext(context.Background(), NewPostBuilder("Hello").DisableQuotes())
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.applyWrites"); n != 1 {
+		t.Errorf("wanted 1 applyWrites call, got %d", n)
+	}
+	if uris := pds.uris("app.bsky.feed.post"); len(uris) != 1 || uris[0] != ref {
+		t.Errorf("wanted only post %s, got %v", ref, uris)
+	}
+}
+
+func TestPostThreadGateOnLaterPost(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	_, err := client.PostThread(
+		NewPostBuilder("First"),
+		NewPostBuilder("Second").RestrictReplies(FollowingCanReply),
+	)
+	var te *ThreadError
+	if !errors.As(err, &te) || te.Index != 1 || !errors.Is(err, ErrInvalidPost) {
+		t.Fatalf("wanted *ThreadError at index 1 wrapping ErrInvalidPost, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.createRecord"); n != 0 {
+		t.Errorf("wanted no createRecord calls, got %d", n)
+	}
+}
+
+func TestPostThreadGateOnFirstPost(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	refs, err := client.PostThread(
+		NewPostBuilder("First").RestrictReplies(FollowingCanReply),
+		NewPostBuilder("Second"),
+	)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if uris := pds.uris("app.bsky.feed.threadgate"); len(uris) != 1 {
+		t.Errorf("wanted 1 threadgate, got %v", uris)
+	}
+	if len(refs) != 2 {
+		t.Errorf("wanted 2 posts, got %d", len(refs))
+	}
+}
@@ -2,4 +2,7 @@ module github.com/fflewddur/ltbsky
 
 go 1.23.0
 
-require golang.org/x/image v0.30.0
+require (
+	github.com/coder/websocket v1.8.14
+	golang.org/x/image v0.30.0
+)
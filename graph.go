@@ -0,0 +1,61 @@
+package ltbsky
+
+import (
+	"context"
+	"fmt"
+)
+
+// Block blocks the account with the given handle or DID and returns the URI
+// of the block record. Blocks are public.
+func (c *Client) Block(ctx context.Context, actor string) (string, error) {
+	return c.createGraphRecord(ctx, "app.bsky.graph.block", actor)
+}
+
+// Unblock removes a block. actor is either the URI of the block record, as
+// returned by Block, or the handle or DID of the blocked account. Unblocking
+// an account that is not blocked is not an error.
+func (c *Client) Unblock(ctx context.Context, actor string) error {
+	return c.deleteGraphRecord(ctx, "app.bsky.graph.block", actor, func(v *ActorViewer) string {
+		return v.Blocking
+	})
+}
+
+// Mute mutes the account with the given handle or DID. Mutes are private:
+// they are stored by the server, not in the user's repo.
+func (c *Client) Mute(ctx context.Context, actor string) error {
+	return c.graphProcedure(ctx, "app.bsky.graph.muteActor", map[string]string{"actor": actor})
+}
+
+// Unmute unmutes the account with the given handle or DID.
+func (c *Client) Unmute(ctx context.Context, actor string) error {
+	return c.graphProcedure(ctx, "app.bsky.graph.unmuteActor", map[string]string{"actor": actor})
+}
+
+// MuteThread mutes notifications from the thread whose root post is at
+// rootURI.
+func (c *Client) MuteThread(ctx context.Context, rootURI string) error {
+	return c.graphProcedure(ctx, "app.bsky.graph.muteThread", map[string]string{"root": rootURI})
+}
+
+// UnmuteThread unmutes the thread whose root post is at rootURI.
+func (c *Client) UnmuteThread(ctx context.Context, rootURI string) error {
+	return c.graphProcedure(ctx, "app.bsky.graph.unmuteThread", map[string]string{"root": rootURI})
+}
+
+// graphProcedure calls one of the mute procedures, which have no output.
+func (c *Client) graphProcedure(ctx context.Context, nsid string, body map[string]string) error {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "POST",
+		nsid:   nsid,
+		body:   body,
+		token:  token,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %w", nsid, err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestBlockAndUnblock(t *testing.T) {
+	pds := newFakePDS(t)
+	var blockURI string
+	pds.on("app.bsky.actor.getProfile", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"did":    "did:plc:spam-example-com",
+			"viewer": map[string]string{"blocking": blockURI},
+		})
+	})
+	client := pds.newClient(t)
+
+	blockURI, err := client.Block(context.Background(), "spam.example.com")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	record := pds.record(blockURI)
+	if record["$type"] != "app.bsky.graph.block" || record["subject"] != "did:plc:spam-example-com" {
+		t.Errorf("wanted a block of did:plc:spam-example-com, got %v", record)
+	}
+
+	if err := client.Unblock(context.Background(), "spam.example.com"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if record := pds.record(blockURI); record != nil {
+		t.Errorf("wanted block to be deleted, got %v", record)
+	}
+
+	// Unblocking again is not an error
+	blockURI = ""
+	if err := client.Unblock(context.Background(), "spam.example.com"); err != nil {
+		t.Errorf("wanted no error, got %v", err)
+	}
+}
+
+func TestUnblockByRecordURI(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	blockURI, err := client.Block(context.Background(), "did:plc:spam")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if err := client.Unblock(context.Background(), blockURI); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if record := pds.record(blockURI); record != nil {
+		t.Errorf("wanted block to be deleted, got %v", record)
+	}
+	if n := pds.count("app.bsky.actor.getProfile"); n != 0 {
+		t.Errorf("wanted no getProfile requests, got %d", n)
+	}
+}
+
+func TestMutes(t *testing.T) {
+	pds := newFakePDS(t)
+	bodies := make(map[string]map[string]string)
+	for _, nsid := range []string{"app.bsky.graph.muteActor", "app.bsky.graph.unmuteActor", "app.bsky.graph.muteThread", "app.bsky.graph.unmuteThread"} {
+		pds.on(nsid, func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("wanted no error, got %v", err)
+			}
+			bodies[nsid] = body
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+	client := pds.newClient(t)
+	ctx := context.Background()
+	root := "at://did:plc:alice/app.bsky.feed.post/3kroot"
+
+	for _, err := range []error{
+		client.Mute(ctx, "spam.example.com"),
+		client.Unmute(ctx, "did:plc:spam"),
+		client.MuteThread(ctx, root),
+		client.UnmuteThread(ctx, root),
+	} {
+		if err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+	}
+	want := map[string][2]string{
+		"app.bsky.graph.muteActor":    {"actor", "spam.example.com"},
+		"app.bsky.graph.unmuteActor":  {"actor", "did:plc:spam"},
+		"app.bsky.graph.muteThread":   {"root", root},
+		"app.bsky.graph.unmuteThread": {"root", root},
+	}
+	for nsid, kv := range want {
+		if got := bodies[nsid][kv[0]]; got != kv[1] {
+			t.Errorf("wanted %s %s %s, got '%s'", nsid, kv[0], kv[1], got)
+		}
+	}
+}
+
+func TestMuteError(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("app.bsky.graph.muteActor", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", "Cannot mute oneself")
+	})
+	client := pds.newClient(t)
+
+	if err := client.Mute(context.Background(), "test.handle"); err == nil {
+		t.Error("wanted an error, got nil")
+	}
+}
@@ -0,0 +1,105 @@
+package ltbsky
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Bluesky measures post length in grapheme clusters: user-perceived
+// characters such as "é", "👍🏽" or "🇨🇦", which can span several code
+// points. The helpers below implement the parts of the Unicode segmentation
+// rules (UAX #29) that matter for post text: combining marks, emoji modifiers
+// and variation selectors, zero-width-joiner sequences, flags, and CRLF.
+
+const zeroWidthJoiner = '\u200d'
+
+// graphemeCount returns the number of grapheme clusters in s.
+func graphemeCount(s string) int {
+	n := 0
+	for len(s) > 0 {
+		s = s[graphemeLen(s):]
+		n++
+	}
+	return n
+}
+
+// graphemeLen returns the length in bytes of the first grapheme cluster in s.
+func graphemeLen(s string) int {
+	first, i := utf8.DecodeRuneInString(s)
+	if i == 0 {
+		return 0
+	}
+	if first == '\r' && len(s) > 1 && s[1] == '\n' {
+		return 2
+	}
+	prev := first
+	pairedFlag := false
+	// A zero-width joiner only joins two pictographs, such as the people in a
+	// family emoji
+	pictographic := isExtendedPictographic(first)
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case isGraphemeExtend(r):
+		case prev == zeroWidthJoiner && pictographic && isExtendedPictographic(r):
+		case !pairedFlag && isRegionalIndicator(prev) && isRegionalIndicator(r):
+			pairedFlag = true
+		default:
+			return i
+		}
+		prev = r
+		i += size
+	}
+	return i
+}
+
+// isGraphemeExtend reports whether r never starts a grapheme cluster of its
+// own, but extends the one before it.
+func isGraphemeExtend(r rune) bool {
+	switch {
+	case r == zeroWidthJoiner:
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // variation selectors
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // emoji skin tone modifiers
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tag characters, used in subdivision flags
+		return true
+	case r >= 0xE0100 && r <= 0xE01EF: // variation selectors supplement
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isExtendedPictographic reports whether r is an emoji or other pictograph
+// that can be joined to another with a zero-width joiner, following the
+// Extended_Pictographic property of UAX #29.
+func isExtendedPictographic(r rune) bool {
+	switch {
+	case r == 0x00A9, r == 0x00AE, r == 0x203C, r == 0x2049, r == 0x2122, r == 0x2139:
+		return true
+	case r >= 0x2194 && r <= 0x2199, r >= 0x21A9 && r <= 0x21AA:
+		return true
+	case r >= 0x231A && r <= 0x231B, r == 0x2328, r == 0x2388, r == 0x23CF:
+		return true
+	case r >= 0x23E9 && r <= 0x23F3, r >= 0x23F8 && r <= 0x23FA, r == 0x24C2:
+		return true
+	case r >= 0x25AA && r <= 0x25AB, r == 0x25B6, r == 0x25C0, r >= 0x25FB && r <= 0x25FE:
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2934 && r <= 0x2935, r >= 0x2B05 && r <= 0x2B07, r >= 0x2B1B && r <= 0x2B1C:
+		return true
+	case r == 0x2B50, r == 0x2B55, r == 0x3030, r == 0x303D, r == 0x3297, r == 0x3299:
+		return true
+	case r >= 0x1F000 && r <= 0x1FAFF: // emoji blocks
+		return !isRegionalIndicator(r) && !(r >= 0x1F3FB && r <= 0x1F3FF)
+	case r >= 0x1FC00 && r <= 0x1FFFD:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package ltbsky
+
+import "testing"
+
+func TestGraphemeCount(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want int
+	}{
+		{name: "Empty", s: "", want: 0},
+		{name: "ASCII", s: "Hello, world!", want: 13},
+		{name: "Precomposed accent", s: "café", want: 4},
+		{name: "Combining accent", s: "café", want: 4},
+		{name: "CJK", s: "こんにちは世界", want: 7},
+		{name: "Emoji", s: "👍", want: 1},
+		{name: "Emoji with skin tone", s: "👍🏽", want: 1},
+		{name: "Emoji with variation selector", s: "❤️", want: 1},
+		{name: "ZWJ family", s: "👨‍👩‍👧‍👦", want: 1},
+		{name: "ZWJ with variation selector", s: "❤️\u200d🔥", want: 1},
+		{name: "ZWJ between letters", s: "a\u200db", want: 2},
+		{name: "ZWJ from emoji to letter", s: "👍\u200da", want: 2},
+		{name: "ZWJ from letter to emoji", s: "a\u200d👍", want: 2},
+		{name: "Flags", s: "🇨🇦🇺🇸", want: 2},
+		{name: "Subdivision flag", s: "🏴󠁧󠁢󠁳󠁣󠁴󠁿", want: 1},
+		{name: "CRLF", s: "a\r\nb", want: 3},
+		{name: "Mixed", s: "Go 🐹 rocks! 🇯🇵", want: 13},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := graphemeCount(tt.s); got != tt.want {
+				t.Errorf("wanted %d graphemes, got %d", tt.want, got)
+			}
+		})
+	}
+}
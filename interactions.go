@@ -0,0 +1,207 @@
+package ltbsky
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A SubjectRecord is an app.bsky.feed.like or app.bsky.feed.repost record.
+// Its subject is the liked or reposted post.
+type SubjectRecord struct {
+	Type      string    `json:"$type"`
+	Subject   StrongRef `json:"subject"`
+	CreatedAt string    `json:"createdAt"`
+}
+
+// A FollowRecord is an app.bsky.graph.follow or app.bsky.graph.block record.
+// Its subject is the DID of the followed or blocked account.
+type FollowRecord struct {
+	Type      string `json:"$type"`
+	Subject   string `json:"subject"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Like likes the post at postURI and returns the URI of the like record.
+func (c *Client) Like(ctx context.Context, postURI string) (string, error) {
+	return c.createSubjectRecord(ctx, "app.bsky.feed.like", postURI)
+}
+
+// Repost reposts the post at postURI and returns the URI of the repost
+// record.
+func (c *Client) Repost(ctx context.Context, postURI string) (string, error) {
+	return c.createSubjectRecord(ctx, "app.bsky.feed.repost", postURI)
+}
+
+// Follow follows the account with the given handle or DID and returns the
+// URI of the follow record.
+func (c *Client) Follow(ctx context.Context, actor string) (string, error) {
+	return c.createGraphRecord(ctx, "app.bsky.graph.follow", actor)
+}
+
+// Unlike removes a like. uri is either the URI of the like record, as
+// returned by Like, or the URI of the liked post. Unliking a post that is not
+// liked is not an error.
+func (c *Client) Unlike(ctx context.Context, uri string) error {
+	return c.deleteSubjectRecord(ctx, "app.bsky.feed.like", uri)
+}
+
+// Unrepost removes a repost. uri is either the URI of the repost record, as
+// returned by Repost, or the URI of the reposted post. Unreposting a post that
+// is not reposted is not an error.
+func (c *Client) Unrepost(ctx context.Context, uri string) error {
+	return c.deleteSubjectRecord(ctx, "app.bsky.feed.repost", uri)
+}
+
+// Unfollow stops following an account. actor is either the URI of the follow
+// record, as returned by Follow, or the handle or DID of the followed
+// account. Unfollowing an account that is not followed is not an error.
+func (c *Client) Unfollow(ctx context.Context, actor string) error {
+	return c.deleteGraphRecord(ctx, "app.bsky.graph.follow", actor, func(v *ActorViewer) string {
+		return v.Following
+	})
+}
+
+// createSubjectRecord creates a like or repost of the post at postURI.
+func (c *Client) createSubjectRecord(ctx context.Context, collection, postURI string) (string, error) {
+	u, err := parseATURI(postURI)
+	if err != nil {
+		return "", err
+	}
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error authenticating: %w", err)
+	}
+	post, err := c.getRecord(ctx, token, u.repo, u.collection, u.rkey)
+	if err != nil {
+		return "", fmt.Errorf("error fetching post %s: %w", postURI, err)
+	}
+	record := &SubjectRecord{
+		Type:      collection,
+		Subject:   StrongRef{URI: post.Uri, CID: post.Cid},
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	resp, err := c.createRecord(ctx, token, c.handle, collection, newTID(), record)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s record: %w", collection, err)
+	}
+	return resp.Uri, nil
+}
+
+// deleteSubjectRecord deletes a like or repost, given either its own URI or
+// the URI of the post. In the latter case, the record is found through the
+// viewer state of the post.
+func (c *Client) deleteSubjectRecord(ctx context.Context, collection, uri string) error {
+	u, err := parseATURI(uri)
+	if err != nil {
+		return err
+	}
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+	if u.collection != collection {
+		posts, err := c.getPosts(ctx, token, []string{uri})
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			return fmt.Errorf("error fetching post %s: %w", uri, ErrRecordNotFound)
+		}
+		if posts[0].Viewer == nil {
+			return nil
+		}
+		uri = posts[0].Viewer.Like
+		if collection == "app.bsky.feed.repost" {
+			uri = posts[0].Viewer.Repost
+		}
+		if uri == "" {
+			return nil
+		}
+	}
+	return c.deleteOwnRecord(ctx, token, collection, uri)
+}
+
+// deleteOwnRecord deletes the record at uri, which must be in collection.
+func (c *Client) deleteOwnRecord(ctx context.Context, token, collection, uri string) error {
+	u, err := parseATURI(uri)
+	if err != nil {
+		return err
+	}
+	if u.collection != collection {
+		return fmt.Errorf("%s is not a %s record", uri, collection)
+	}
+	err = c.deleteRecord(ctx, token, u.repo, u.collection, u.rkey, "")
+	if err != nil {
+		return fmt.Errorf("error deleting %s: %w", uri, err)
+	}
+	return nil
+}
+
+// createGraphRecord creates a follow or block of the account with the given
+// handle or DID.
+func (c *Client) createGraphRecord(ctx context.Context, collection, actor string) (string, error) {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error authenticating: %w", err)
+	}
+	did, err := c.resolveActor(ctx, token, actor)
+	if err != nil {
+		return "", err
+	}
+	record := &FollowRecord{
+		Type:      collection,
+		Subject:   did,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	resp, err := c.createRecord(ctx, token, c.handle, collection, newTID(), record)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s record for %s: %w", collection, actor, err)
+	}
+	return resp.Uri, nil
+}
+
+// deleteGraphRecord deletes a follow or block, given either its own URI or
+// the handle or DID of the account. In the latter case, the record is found
+// through the viewer state of the account's profile, using uriOf.
+func (c *Client) deleteGraphRecord(ctx context.Context, collection, actor string, uriOf func(*ActorViewer) string) error {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+	uri := actor
+	if !strings.HasPrefix(actor, "at://") {
+		profile, err := c.GetProfile(ctx, actor)
+		if err != nil {
+			return err
+		}
+		if profile.Viewer == nil || uriOf(profile.Viewer) == "" {
+			return nil
+		}
+		uri = uriOf(profile.Viewer)
+	}
+	return c.deleteOwnRecord(ctx, token, collection, uri)
+}
+
+// resolveActor returns the DID of the account with the given handle or DID.
+func (c *Client) resolveActor(ctx context.Context, token, actor string) (string, error) {
+	actor = strings.TrimPrefix(actor, "@")
+	if strings.HasPrefix(actor, "did:") {
+		return actor, nil
+	}
+	var resp struct {
+		Did string `json:"did"`
+	}
+	err := c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "com.atproto.identity.resolveHandle",
+		params: url.Values{"handle": {actor}},
+		token:  token,
+	}, &resp)
+	if err != nil {
+		return "", fmt.Errorf("error resolving handle %s: %w", actor, err)
+	}
+	return resp.Did, nil
+}
@@ -0,0 +1,156 @@
+package ltbsky
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLikeAndRepost(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection string
+		create     func(*Client, context.Context, string) (string, error)
+	}{
+		{name: "Like", collection: "app.bsky.feed.like", create: (*Client).Like},
+		{name: "Repost", collection: "app.bsky.feed.repost", create: (*Client).Repost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pds := newFakePDS(t)
+			postURI := "at://did:plc:other/app.bsky.feed.post/3kpost"
+			postCid := pds.seed(postURI, map[string]any{"$type": "app.bsky.feed.post", "text": "Nice"})
+			client := pds.newClient(t)
+
+			uri, err := tt.create(client, context.Background(), postURI)
+			if err != nil {
+				t.Fatalf("wanted no error, got %v", err)
+			}
+			if !strings.HasPrefix(uri, "at://did:plc:test/"+tt.collection+"/") {
+				t.Errorf("wanted a %s URI, got %s", tt.collection, uri)
+			}
+			record := pds.record(uri)
+			if record["$type"] != tt.collection {
+				t.Errorf("wanted $type %s, got %v", tt.collection, record["$type"])
+			}
+			wantRef := map[string]any{"uri": postURI, "cid": postCid}
+			if !equalRef(record["subject"], wantRef) {
+				t.Errorf("wanted subject %v, got %v", wantRef, record["subject"])
+			}
+		})
+	}
+}
+
+func TestUnlikeByRecordURI(t *testing.T) {
+	pds := newFakePDS(t)
+	likeURI := "at://did:plc:test/app.bsky.feed.like/3klike"
+	pds.seed(likeURI, map[string]any{"$type": "app.bsky.feed.like"})
+	client := pds.newClient(t)
+
+	if err := client.Unlike(context.Background(), likeURI); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if record := pds.record(likeURI); record != nil {
+		t.Errorf("wanted like to be deleted, got %v", record)
+	}
+	if n := pds.count("app.bsky.feed.getPosts"); n != 0 {
+		t.Errorf("wanted no getPosts calls, got %d", n)
+	}
+}
+
+func TestUndoByViewerState(t *testing.T) {
+	pds := newFakePDS(t)
+	postURI := "at://did:plc:other/app.bsky.feed.post/3kpost"
+	likeURI := "at://did:plc:test/app.bsky.feed.like/3klike"
+	repostURI := "at://did:plc:test/app.bsky.feed.repost/3krepost"
+	pds.seed(likeURI, map[string]any{"$type": "app.bsky.feed.like"})
+	pds.seed(repostURI, map[string]any{"$type": "app.bsky.feed.repost"})
+	pds.on("app.bsky.feed.getPosts", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("uris"); got != postURI {
+			t.Errorf("wanted uris %s, got %s", postURI, got)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"posts": []map[string]any{{
+			"uri":    postURI,
+			"viewer": map[string]string{"like": likeURI, "repost": repostURI},
+		}}})
+	})
+	client := pds.newClient(t)
+
+	if err := client.Unlike(context.Background(), postURI); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if record := pds.record(likeURI); record != nil {
+		t.Errorf("wanted like to be deleted, got %v", record)
+	}
+	if pds.record(repostURI) == nil {
+		t.Fatal("wanted repost to be kept, got nil")
+	}
+	if err := client.Unrepost(context.Background(), postURI); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if record := pds.record(repostURI); record != nil {
+		t.Errorf("wanted repost to be deleted, got %v", record)
+	}
+}
+
+func TestUnlikeNotLiked(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("app.bsky.feed.getPosts", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"posts": []map[string]any{{"viewer": map[string]string{}}}})
+	})
+	client := pds.newClient(t)
+
+	if err := client.Unlike(context.Background(), "at://did:plc:other/app.bsky.feed.post/3kpost"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.deleteRecord"); n != 0 {
+		t.Errorf("wanted no deleteRecord calls, got %d", n)
+	}
+}
+
+func TestFollowAndUnfollow(t *testing.T) {
+	pds := newFakePDS(t)
+	var followURI string
+	pds.on("app.bsky.actor.getProfile", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("actor"); got != "alice.bsky.social" {
+			t.Errorf("wanted actor alice.bsky.social, got %s", got)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"did":    "did:plc:alice-bsky-social",
+			"viewer": map[string]string{"following": followURI},
+		})
+	})
+	client := pds.newClient(t)
+
+	followURI, err := client.Follow(context.Background(), "@alice.bsky.social")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	record := pds.record(followURI)
+	if record["$type"] != "app.bsky.graph.follow" {
+		t.Errorf("wanted $type app.bsky.graph.follow, got %v", record["$type"])
+	}
+	if record["subject"] != "did:plc:alice-bsky-social" {
+		t.Errorf("wanted subject did:plc:alice-bsky-social, got %v", record["subject"])
+	}
+
+	if err := client.Unfollow(context.Background(), "alice.bsky.social"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if record := pds.record(followURI); record != nil {
+		t.Errorf("wanted follow to be deleted, got %v", record)
+	}
+}
+
+func TestUnfollowWrongCollection(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	if err := client.Unfollow(context.Background(), "at://did:plc:test/app.bsky.feed.like/3klike"); err == nil {
+		t.Fatal("wanted error, got nil")
+	}
+	if n := pds.count("com.atproto.repo.deleteRecord"); n != 0 {
+		t.Errorf("wanted no deleteRecord calls, got %d", n)
+	}
+}
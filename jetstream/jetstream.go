@@ -0,0 +1,322 @@
+// Package jetstream subscribes to a Jetstream server, which streams the
+// events of the Bluesky network as JSON over a WebSocket. A Subscriber
+// filters the stream by collection and account, reconnects with backoff when
+// the connection drops, and can save its position in an ltbsky.CursorStore so
+// it resumes where it stopped.
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/fflewddur/ltbsky"
+)
+
+// DefaultURL is the subscribe endpoint of one of Bluesky's public Jetstream
+// servers.
+const DefaultURL = "wss://jetstream2.us-east.bsky.network/subscribe"
+
+// Default backoff between reconnects.
+const (
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+)
+
+// Kinds of events.
+const (
+	KindCommit   = "commit"
+	KindIdentity = "identity"
+	KindAccount  = "account"
+)
+
+// Operations of commit events.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// maxMessageSize is the largest event the Subscriber accepts.
+const maxMessageSize = 2 << 20
+
+// saveInterval is how often a Subscriber saves its cursor while events are
+// arriving.
+const saveInterval = time.Second
+
+// An Event is a change to an account: a record written or deleted, a new
+// handle, or a change in the account's status. Which of Commit, Identity, and
+// Account is set depends on Kind.
+type Event struct {
+	Did string `json:"did"`
+	// TimeUS is when the server received the event, in microseconds since
+	// the Unix epoch. It is also the event's cursor.
+	TimeUS   int64     `json:"time_us"`
+	Kind     string    `json:"kind"`
+	Commit   *Commit   `json:"commit,omitempty"`
+	Identity *Identity `json:"identity,omitempty"`
+	Account  *Account  `json:"account,omitempty"`
+}
+
+// A Commit is a record that was created, updated, or deleted. Record and CID
+// are empty for deletes.
+type Commit struct {
+	Rev        string          `json:"rev"`
+	Operation  string          `json:"operation"`
+	Collection string          `json:"collection"`
+	RKey       string          `json:"rkey"`
+	Record     json.RawMessage `json:"record,omitempty"`
+	CID        string          `json:"cid,omitempty"`
+}
+
+// An Identity event reports that an account's handle or DID document may have
+// changed.
+type Identity struct {
+	Did    string    `json:"did"`
+	Handle string    `json:"handle,omitempty"`
+	Seq    int64     `json:"seq"`
+	Time   time.Time `json:"time"`
+}
+
+// An Account event reports that an account was activated or deactivated.
+// Status says why an inactive account is inactive, such as "takendown".
+type Account struct {
+	Active bool      `json:"active"`
+	Did    string    `json:"did"`
+	Seq    int64     `json:"seq"`
+	Status string    `json:"status,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Time returns when the server received the event.
+func (e *Event) Time() time.Time {
+	return time.UnixMicro(e.TimeUS)
+}
+
+// URI returns the AT-URI of the record of a commit event, or "" for other
+// events.
+func (e *Event) URI() string {
+	if e.Commit == nil {
+		return ""
+	}
+	return "at://" + e.Did + "/" + e.Commit.Collection + "/" + e.Commit.RKey
+}
+
+// Record decodes the record of a commit event. Posts are returned as
+// *ltbsky.PostRecord, likes and reposts as *ltbsky.SubjectRecord, follows as
+// *ltbsky.FollowRecord, and records of other collections as map[string]any.
+// It returns an error for deletes and events that are not commits.
+func (e *Event) Record() (any, error) {
+	if e.Commit == nil || len(e.Commit.Record) == 0 {
+		return nil, fmt.Errorf("event for %s has no record", e.Did)
+	}
+	var record any
+	switch e.Commit.Collection {
+	case "app.bsky.feed.post":
+		record = &ltbsky.PostRecord{}
+	case "app.bsky.feed.like", "app.bsky.feed.repost":
+		record = &ltbsky.SubjectRecord{}
+	case "app.bsky.graph.follow":
+		record = &ltbsky.FollowRecord{}
+	default:
+		record = &map[string]any{}
+	}
+	if err := json.Unmarshal(e.Commit.Record, record); err != nil {
+		return nil, fmt.Errorf("error unmarshaling record %s: %w", e.URI(), err)
+	}
+	if m, ok := record.(*map[string]any); ok {
+		return *m, nil
+	}
+	return record, nil
+}
+
+// A Handler handles an event. If it returns an error, the Subscriber
+// reconnects and receives the same event again.
+type Handler func(ctx context.Context, e *Event) error
+
+// A Subscriber receives events from a Jetstream server. The zero value
+// receives every event from DefaultURL, starting with live events.
+type Subscriber struct {
+	// URL is the server's subscribe endpoint. Empty means DefaultURL.
+	URL string
+	// Collections limits commit events to the given collections, such as
+	// "app.bsky.feed.post". A name can end in ".*" to match every collection
+	// with that prefix. Empty means all collections.
+	Collections []string
+	// DIDs limits events to the given accounts. Empty means all accounts.
+	DIDs []string
+	// Store, if set, keeps the Subscriber's cursor between runs.
+	Store ltbsky.CursorStore
+	// Key is the key of the cursor in Store. Empty means "jetstream".
+	Key string
+	// Cursor is the time, in microseconds since the Unix epoch, to start
+	// from when Store has no cursor. Zero means live events.
+	Cursor int64
+	// MinBackoff and MaxBackoff bound the wait before reconnecting, which
+	// doubles after each failed connection. Zero means DefaultMinBackoff and
+	// DefaultMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// HTTPClient is used to connect. Nil means http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Run receives events and passes each one to handler, in order, until ctx is
+// done. It then saves the cursor and returns ctx.Err(). Connection and
+// handler errors are logged, and Run reconnects after a backoff, resuming
+// from the last handled event.
+func (s *Subscriber) Run(ctx context.Context, handler Handler) error {
+	cursor, err := s.load()
+	if err != nil {
+		return err
+	}
+	backoff := s.minBackoff()
+	for {
+		handled, err := s.stream(ctx, &cursor, handler)
+		if saveErr := s.save(cursor); saveErr != nil {
+			log.Printf("Error saving Jetstream cursor: %v", saveErr)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if handled {
+			backoff = s.minBackoff()
+		}
+		log.Printf("Jetstream connection lost, reconnecting in %v: %v", backoff, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, s.maxBackoff())
+	}
+}
+
+// stream connects once and handles events until the connection fails or a
+// handler returns an error. It advances cursor past each handled event, and
+// reports whether any event was handled.
+func (s *Subscriber) stream(ctx context.Context, cursor *int64, handler Handler) (handled bool, err error) {
+	u, err := s.subscribeURL(*cursor)
+	if err != nil {
+		return false, err
+	}
+	conn, _, err := websocket.Dial(ctx, u, &websocket.DialOptions{HTTPClient: s.HTTPClient})
+	if err != nil {
+		return false, fmt.Errorf("error connecting to %s: %w", u, err)
+	}
+	defer func() {
+		// A connection that was already closed reports net.ErrClosed
+		if closeErr := conn.CloseNow(); !errors.Is(closeErr, net.ErrClosed) {
+			err = errors.Join(err, closeErr)
+		}
+	}()
+	conn.SetReadLimit(maxMessageSize)
+
+	saved := time.Now()
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return handled, fmt.Errorf("error reading event: %w", err)
+		}
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			log.Printf("Error unmarshaling Jetstream event: %v", err)
+			continue
+		}
+		// The server may replay events from before the cursor
+		if e.TimeUS <= *cursor {
+			continue
+		}
+		if err := handler(ctx, &e); err != nil {
+			err = fmt.Errorf("error handling event %d: %w", e.TimeUS, err)
+			return handled, errors.Join(err, conn.Close(websocket.StatusNormalClosure, ""))
+		}
+		*cursor = e.TimeUS
+		handled = true
+		if time.Since(saved) >= saveInterval {
+			if err := s.save(*cursor); err != nil {
+				log.Printf("Error saving Jetstream cursor: %v", err)
+			}
+			saved = time.Now()
+		}
+	}
+}
+
+func (s *Subscriber) subscribeURL(cursor int64) (string, error) {
+	raw := s.URL
+	if raw == "" {
+		raw = DefaultURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("error parsing Jetstream URL %s: %w", raw, err)
+	}
+	q := u.Query()
+	for _, c := range s.Collections {
+		q.Add("wantedCollections", c)
+	}
+	for _, did := range s.DIDs {
+		q.Add("wantedDids", did)
+	}
+	if cursor > 0 {
+		q.Set("cursor", strconv.FormatInt(cursor, 10))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (s *Subscriber) key() string {
+	if s.Key != "" {
+		return s.Key
+	}
+	return "jetstream"
+}
+
+func (s *Subscriber) load() (int64, error) {
+	if s.Store == nil {
+		return s.Cursor, nil
+	}
+	saved, err := s.Store.Load(s.key())
+	if err != nil {
+		return 0, fmt.Errorf("error loading Jetstream cursor: %w", err)
+	}
+	if saved == "" {
+		return s.Cursor, nil
+	}
+	cursor, err := strconv.ParseInt(saved, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing Jetstream cursor %q: %w", saved, err)
+	}
+	return cursor, nil
+}
+
+func (s *Subscriber) save(cursor int64) error {
+	if s.Store == nil || cursor == 0 {
+		return nil
+	}
+	return s.Store.Save(s.key(), strconv.FormatInt(cursor, 10))
+}
+
+func (s *Subscriber) minBackoff() time.Duration {
+	if s.MinBackoff > 0 {
+		return s.MinBackoff
+	}
+	return DefaultMinBackoff
+}
+
+func (s *Subscriber) maxBackoff() time.Duration {
+	if s.MaxBackoff > 0 {
+		return max(s.MaxBackoff, s.minBackoff())
+	}
+	return max(DefaultMaxBackoff, s.minBackoff())
+}
@@ -0,0 +1,274 @@
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/fflewddur/ltbsky"
+)
+
+// fakeJetstream is a stand-in for a Jetstream server. It sends its events
+// from the requested cursor on, including the event at the cursor, and then
+// keeps the connection open.
+type fakeJetstream struct {
+	*httptest.Server
+	events []string
+	// dropAfter closes each connection after that many events, if set.
+	dropAfter int
+
+	mu      sync.Mutex
+	queries []url.Values
+}
+
+func newFakeJetstream(t *testing.T, events ...string) *fakeJetstream {
+	f := &fakeJetstream{events: events}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeJetstream) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		// The client usually closes the connection first
+		_ = conn.CloseNow()
+	}()
+	q := r.URL.Query()
+	f.mu.Lock()
+	f.queries = append(f.queries, q)
+	f.mu.Unlock()
+
+	cursor, _ := strconv.ParseInt(q.Get("cursor"), 10, 64)
+	sent := 0
+	for _, e := range f.events {
+		var ev Event
+		if err := json.Unmarshal([]byte(e), &ev); err == nil && ev.TimeUS < cursor {
+			continue
+		}
+		if err := conn.Write(r.Context(), websocket.MessageText, []byte(e)); err != nil {
+			return
+		}
+		sent++
+		if sent == f.dropAfter {
+			_ = conn.Close(websocket.StatusGoingAway, "")
+			return
+		}
+	}
+	// Keep the connection open until the client closes it
+	_, _, _ = conn.Read(r.Context())
+}
+
+func (f *fakeJetstream) query(i int) url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i >= len(f.queries) {
+		return nil
+	}
+	return f.queries[i]
+}
+
+func (f *fakeJetstream) subscriber() *Subscriber {
+	return &Subscriber{
+		URL:        "ws" + strings.TrimPrefix(f.URL, "http"),
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+	}
+}
+
+func postEvent(timeUS int64, text string) string {
+	return fmt.Sprintf(`{"did":"did:plc:alice","time_us":%d,"kind":"commit","commit":{"rev":"3k","operation":"create","collection":"app.bsky.feed.post","rkey":"3k%d","record":{"$type":"app.bsky.feed.post","text":%q,"createdAt":"2024-01-02T03:04:05Z"},"cid":"bafy"}}`, timeUS, timeUS, text)
+}
+
+// collect returns a handler that records the time of each event and cancels
+// ctx after n events.
+func collect(got *[]int64, n int, cancel context.CancelFunc) Handler {
+	return func(ctx context.Context, e *Event) error {
+		*got = append(*got, e.TimeUS)
+		if len(*got) == n {
+			cancel()
+		}
+		return nil
+	}
+}
+
+// run runs s until handler cancels ctx, and returns the error from Run.
+func run(t *testing.T, s *Subscriber, handler func(cancel context.CancelFunc) Handler) error {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, handler(cancel)) }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("wanted Run to return after cancel")
+		return nil
+	}
+}
+
+func TestSubscriber(t *testing.T) {
+	js := newFakeJetstream(t, postEvent(1000, "one"), postEvent(2000, "two"))
+	store := ltbsky.NewMemoryCursorStore()
+	s := js.subscriber()
+	s.Collections = []string{"app.bsky.feed.post", "app.bsky.graph.*"}
+	s.DIDs = []string{"did:plc:alice"}
+	s.Store = store
+
+	var texts []string
+	err := run(t, s, func(cancel context.CancelFunc) Handler {
+		return func(ctx context.Context, e *Event) error {
+			record, err := e.Record()
+			if err != nil {
+				return err
+			}
+			post, ok := record.(*ltbsky.PostRecord)
+			if !ok {
+				t.Fatalf("wanted *ltbsky.PostRecord, got %T", record)
+			}
+			texts = append(texts, post.Text)
+			if len(texts) == 2 {
+				cancel()
+			}
+			return nil
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("wanted context.Canceled, got %v", err)
+	}
+	if fmt.Sprint(texts) != "[one two]" {
+		t.Errorf("wanted [one two], got %v", texts)
+	}
+	q := js.query(0)
+	if len(q["wantedCollections"]) != 2 || q.Get("wantedDids") != "did:plc:alice" || q.Has("cursor") {
+		t.Errorf("wanted 2 collections, 1 DID, and no cursor, got %v", q)
+	}
+	if cursor, _ := store.Load("jetstream"); cursor != "2000" {
+		t.Errorf("wanted saved cursor 2000, got '%s'", cursor)
+	}
+}
+
+func TestSubscriberReconnect(t *testing.T) {
+	js := newFakeJetstream(t, postEvent(1000, "one"), postEvent(2000, "two"), postEvent(3000, "three"))
+	js.dropAfter = 2
+	s := js.subscriber()
+
+	var got []int64
+	err := run(t, s, func(cancel context.CancelFunc) Handler {
+		return collect(&got, 3, cancel)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("wanted context.Canceled, got %v", err)
+	}
+	if fmt.Sprint(got) != "[1000 2000 3000]" {
+		t.Errorf("wanted each event once, got %v", got)
+	}
+	if cursor := js.query(1).Get("cursor"); cursor != "2000" {
+		t.Errorf("wanted reconnect with cursor 2000, got '%s'", cursor)
+	}
+}
+
+func TestSubscriberHandlerError(t *testing.T) {
+	js := newFakeJetstream(t, postEvent(1000, "one"), postEvent(2000, "two"), postEvent(3000, "three"))
+	s := js.subscriber()
+
+	var got []int64
+	failed := false
+	err := run(t, s, func(cancel context.CancelFunc) Handler {
+		h := collect(&got, 3, cancel)
+		return func(ctx context.Context, e *Event) error {
+			if e.TimeUS == 2000 && !failed {
+				failed = true
+				return errors.New("handler failed")
+			}
+			return h(ctx, e)
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("wanted context.Canceled, got %v", err)
+	}
+	if fmt.Sprint(got) != "[1000 2000 3000]" {
+		t.Errorf("wanted the failed event again, got %v", got)
+	}
+	if cursor := js.query(1).Get("cursor"); cursor != "1000" {
+		t.Errorf("wanted reconnect with cursor 1000, got '%s'", cursor)
+	}
+}
+
+func TestSubscriberResume(t *testing.T) {
+	js := newFakeJetstream(t, postEvent(1000, "one"), postEvent(2000, "two"), postEvent(3000, "three"))
+	store := ltbsky.NewMemoryCursorStore()
+	if err := store.Save("posts", "2000"); err != nil {
+		t.Fatal(err)
+	}
+	s := js.subscriber()
+	s.Store = store
+	s.Key = "posts"
+
+	var got []int64
+	err := run(t, s, func(cancel context.CancelFunc) Handler {
+		return collect(&got, 1, cancel)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("wanted context.Canceled, got %v", err)
+	}
+	if fmt.Sprint(got) != "[3000]" {
+		t.Errorf("wanted [3000], got %v", got)
+	}
+	if cursor := js.query(0).Get("cursor"); cursor != "2000" {
+		t.Errorf("wanted cursor 2000, got '%s'", cursor)
+	}
+	if cursor, _ := store.Load("posts"); cursor != "3000" {
+		t.Errorf("wanted saved cursor 3000, got '%s'", cursor)
+	}
+}
+
+func TestEventRecord(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		want  string
+	}{
+		{"Post", postEvent(1000, "hi"), "*ltbsky.PostRecord"},
+		{"Like", `{"did":"did:plc:alice","kind":"commit","commit":{"operation":"create","collection":"app.bsky.feed.like","rkey":"3k","record":{"$type":"app.bsky.feed.like","subject":{"uri":"at://did:plc:bob/app.bsky.feed.post/3k","cid":"bafy"}}}}`, "*ltbsky.SubjectRecord"},
+		{"Follow", `{"did":"did:plc:alice","kind":"commit","commit":{"operation":"create","collection":"app.bsky.graph.follow","rkey":"3k","record":{"$type":"app.bsky.graph.follow","subject":"did:plc:bob"}}}`, "*ltbsky.FollowRecord"},
+		{"Other", `{"did":"did:plc:alice","kind":"commit","commit":{"operation":"create","collection":"com.example.thing","rkey":"3k","record":{"$type":"com.example.thing"}}}`, "map[string]interface {}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e Event
+			if err := json.Unmarshal([]byte(tt.event), &e); err != nil {
+				t.Fatalf("wanted no error, got %v", err)
+			}
+			record, err := e.Record()
+			if err != nil {
+				t.Fatalf("wanted no error, got %v", err)
+			}
+			if got := fmt.Sprintf("%T", record); got != tt.want {
+				t.Errorf("wanted %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	e := Event{Did: "did:plc:alice", Kind: KindCommit, Commit: &Commit{Operation: OpDelete, Collection: "app.bsky.feed.post", RKey: "3k"}}
+	if e.URI() != "at://did:plc:alice/app.bsky.feed.post/3k" {
+		t.Errorf("wanted at://did:plc:alice/app.bsky.feed.post/3k, got %s", e.URI())
+	}
+	if _, err := e.Record(); err == nil {
+		t.Error("wanted an error for a delete, got nil")
+	}
+}
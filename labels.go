@@ -0,0 +1,78 @@
+package ltbsky
+
+import "slices"
+
+// Self-labels that Bluesky apps understand. Posts with one of them are hidden
+// or shown behind a content warning, depending on each reader's settings.
+const (
+	// LabelSexual marks sexually suggestive content.
+	LabelSexual = "sexual"
+	// LabelNudity marks non-sexual nudity, such as artistic nudes.
+	LabelNudity = "nudity"
+	// LabelPorn marks sexually explicit content.
+	LabelPorn = "porn"
+	// LabelGraphicMedia marks violent or gory content.
+	LabelGraphicMedia = "graphic-media"
+)
+
+// Limits on the self-labels of a post, enforced by PostBuilder.Validate.
+const (
+	// MaxSelfLabels is the maximum number of self-labels on a post.
+	MaxSelfLabels = 10
+	// MaxLabelBytes is the maximum length of a label value in UTF-8 bytes.
+	MaxLabelBytes = 128
+)
+
+// knownSelfLabels are the values accepted by AddSelfLabel.
+var knownSelfLabels = []string{LabelSexual, LabelNudity, LabelPorn, LabelGraphicMedia}
+
+// SelfLabels are the labels an author puts on their own record.
+type SelfLabels struct {
+	Type   string      `json:"$type"`
+	Values []SelfLabel `json:"values"`
+}
+
+// A SelfLabel is a single label value, such as LabelGraphicMedia.
+type SelfLabel struct {
+	Val string `json:"val"`
+}
+
+// selfLabel is a label added to a PostBuilder. Custom labels are not checked
+// against the known values.
+type selfLabel struct {
+	val    string
+	custom bool
+}
+
+// AddSelfLabel adds a content warning to the post, such as LabelGraphicMedia.
+// Validate reports values that Bluesky apps do not know; use
+// AddCustomSelfLabel for those.
+func (pb *PostBuilder) AddSelfLabel(val string) *PostBuilder {
+	return pb.addSelfLabel(selfLabel{val: val})
+}
+
+// AddCustomSelfLabel adds a label value to the post without checking that
+// Bluesky apps know it.
+func (pb *PostBuilder) AddCustomSelfLabel(val string) *PostBuilder {
+	return pb.addSelfLabel(selfLabel{val: val, custom: true})
+}
+
+func (pb *PostBuilder) addSelfLabel(label selfLabel) *PostBuilder {
+	if !slices.ContainsFunc(pb.selfLabels, func(l selfLabel) bool { return l.val == label.val }) {
+		pb.selfLabels = append(pb.selfLabels, label)
+	}
+	return pb
+}
+
+// labels returns the post's self-labels as a record field, or nil if it has
+// none.
+func (pb *PostBuilder) labels() *SelfLabels {
+	if len(pb.selfLabels) == 0 {
+		return nil
+	}
+	labels := &SelfLabels{Type: "com.atproto.label.defs#selfLabels"}
+	for _, l := range pb.selfLabels {
+		labels.Values = append(labels.Values, SelfLabel{Val: l.val})
+	}
+	return labels
+}
@@ -0,0 +1,90 @@
+package ltbsky
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestPostWithSelfLabels(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	pb := NewPostBuilder("Content warning").
+		AddSelfLabel(LabelGraphicMedia).
+		AddCustomSelfLabel("spoiler").
+		AddSelfLabel(LabelGraphicMedia)
+	ref, err := client.PostContext(context.Background(), pb)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	labels, ok := pds.record(ref)["labels"].(map[string]any)
+	if !ok {
+		t.Fatalf("wanted labels, got %v", pds.record(ref)["labels"])
+	}
+	if labels["$type"] != "com.atproto.label.defs#selfLabels" {
+		t.Errorf("wanted selfLabels type, got %v", labels["$type"])
+	}
+	want := "[map[val:graphic-media] map[val:spoiler]]"
+	if got := fmt.Sprint(labels["values"]); got != want {
+		t.Errorf("wanted values %s, got %s", want, got)
+	}
+}
+
+func TestPostWithoutSelfLabels(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	ref, err := client.PostContext(context.Background(), NewPostBuilder("No warning"))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if labels, ok := pds.record(ref)["labels"]; ok {
+		t.Errorf("wanted no labels, got %v", labels)
+	}
+}
+
+func TestPostWithUnknownSelfLabel(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	_, err := client.PostContext(context.Background(), NewPostBuilder("Oops").AddSelfLabel("gore"))
+	if err == nil {
+		t.Fatal("wanted an error, got nil")
+	}
+	if n := pds.count("com.atproto.repo.createRecord"); n != 0 {
+		t.Errorf("wanted no createRecord calls, got %d", n)
+	}
+}
+
+func TestUpdatePostSelfLabels(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := "at://did:plc:test/app.bsky.feed.post/3kpost"
+	pds.seed(uri, map[string]any{
+		"$type":     "app.bsky.feed.post",
+		"text":      "Photos",
+		"createdAt": "2024-01-02T03:04:05Z",
+		"labels": map[string]any{
+			"$type":  "com.atproto.label.defs#selfLabels",
+			"values": []map[string]string{{"val": "porn"}},
+		},
+	})
+	client := pds.newClient(t)
+
+	// The wrong warning is replaced
+	if _, err := client.UpdatePost(context.Background(), uri, NewPostBuilder("Photos").AddSelfLabel(LabelNudity)); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	labels, _ := pds.record(uri)["labels"].(map[string]any)
+	if got := fmt.Sprint(labels["values"]); got != "[map[val:nudity]]" {
+		t.Errorf("wanted values [map[val:nudity]], got %s", got)
+	}
+
+	// And then removed
+	if _, err := client.UpdatePost(context.Background(), uri, NewPostBuilder("Photos")); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if labels, ok := pds.record(uri)["labels"]; ok {
+		t.Errorf("wanted no labels, got %v", labels)
+	}
+}
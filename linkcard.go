@@ -0,0 +1,223 @@
+package ltbsky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	_ "golang.org/x/image/webp" // many pages use WebP preview images
+)
+
+// maxPageBytes is how much of a web page is read when looking for its
+// metadata, and maxThumbBytes the largest preview image that is downloaded.
+const (
+	maxPageBytes  = 1 << 20
+	maxThumbBytes = 10 << 20
+)
+
+// A LinkCard is the preview card shown for a link in a post.
+type LinkCard struct {
+	// URL is the address the card links to.
+	URL string
+	// Title is shown in bold on the card.
+	Title string
+	// Description is shown below the title.
+	Description string
+	// Thumb is the data of the card's preview image, if any. It is scaled
+	// down like other images.
+	Thumb []byte
+}
+
+var (
+	metaTagRegex   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attributeRegex = regexp.MustCompile(`(?is)([a-z:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titleTagRegex  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// AddLinkCard adds a preview card for url to the post. When the post is sent,
+// the page is fetched and its OpenGraph title, description, and image are
+// used for the card. If the page cannot be fetched, the post is sent without
+// a card.
+func (pb *PostBuilder) AddLinkCard(url string) *PostBuilder {
+	pb.linkCard = &LinkCard{URL: url}
+	pb.fetchLinkCard = true
+	pb.firstLinkCard = false
+	return pb
+}
+
+// AddLinkCardFromFirstLink adds a preview card for the first link in the
+// post's text, if there is one, like AddLinkCard. No card is added if the
+// post has images or a video.
+func (pb *PostBuilder) AddLinkCardFromFirstLink() *PostBuilder {
+	pb.linkCard = nil
+	pb.fetchLinkCard = true
+	pb.firstLinkCard = true
+	return pb
+}
+
+// AddCustomLinkCard adds a preview card to the post using the given details
+// as they are, without fetching the page. Use it for pages that cannot be
+// fetched.
+func (pb *PostBuilder) AddCustomLinkCard(card LinkCard) *PostBuilder {
+	pb.linkCard = &card
+	pb.fetchLinkCard = false
+	pb.firstLinkCard = false
+	return pb
+}
+
+// embedLinkCardInPost fetches the post's link card, if needed, uploads its
+// thumbnail, and embeds it in the post record.
+func (c *Client) embedLinkCardInPost(ctx context.Context, pb *PostBuilder, pr *postRequest, token string) error {
+	card := pb.linkCard
+	if pb.firstLinkCard {
+		if len(pb.images) > 0 || pb.video != nil {
+			return nil
+		}
+		for _, d := range detectFacets(pb.content) {
+			if d.kind == facetLink {
+				card = &LinkCard{URL: d.value}
+				break
+			}
+		}
+	}
+	if card == nil {
+		return nil
+	}
+	if pb.fetchLinkCard {
+		fetched, err := c.fetchLinkCard(ctx, card.URL)
+		if err != nil {
+			log.Printf("Error fetching link card for %s: %v", card.URL, err)
+			return nil
+		}
+		card = fetched
+	}
+
+	external := &EmbedExternal{
+		URI:         card.URL,
+		Title:       card.Title,
+		Description: card.Description,
+	}
+	if len(card.Thumb) > 0 {
+		data, mimetype, _, err := prepareImage(card.Thumb)
+		if err != nil {
+			log.Printf("Error preparing thumbnail for %s: %v", card.URL, err)
+		} else {
+			external.Thumb, err = c.uploadBlob(ctx, token, data, mimetype)
+			if err != nil {
+				return fmt.Errorf("error uploading thumbnail for %s: %w", card.URL, err)
+			}
+		}
+	}
+	pr.Record.Embed = &Embed{
+		Type:     "app.bsky.embed.external",
+		External: external,
+	}
+	return nil
+}
+
+// fetchLinkCard fetches the page at pageURL and builds a link card from its
+// metadata. A preview image that cannot be fetched is left out.
+func (c *Client) fetchLinkCard(ctx context.Context, pageURL string) (*LinkCard, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	page, err := c.fetch(ctx, pageURL, maxPageBytes)
+	if err != nil {
+		return nil, err
+	}
+	meta := parsePageMetadata(page)
+	card := &LinkCard{
+		URL:         pageURL,
+		Title:       meta.title,
+		Description: meta.description,
+	}
+	if meta.image != "" {
+		imageURL, err := base.Parse(meta.image)
+		if err != nil {
+			log.Printf("Error parsing preview image URL %s: %v", meta.image, err)
+			return card, nil
+		}
+		card.Thumb, err = c.fetch(ctx, imageURL.String(), maxThumbBytes)
+		if err != nil {
+			log.Printf("Error fetching preview image %s: %v", imageURL, err)
+		}
+	}
+	return card, nil
+}
+
+// fetch returns the body of the resource at u, reading at most limit bytes.
+func (c *Client) fetch(ctx context.Context, u string, limit int64) (data []byte, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "ltbsky (+https://github.com/fflewddur/ltbsky)")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, resp.Body.Close())
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// pageMetadata is the metadata of a web page used for its link card.
+type pageMetadata struct {
+	title       string
+	description string
+	image       string
+}
+
+// parsePageMetadata reads the OpenGraph title, description, and image of an
+// HTML page. Twitter card tags, the description meta tag, and the title
+// element are used when there is no OpenGraph tag.
+func parsePageMetadata(page []byte) pageMetadata {
+	tags := make(map[string]string)
+	for _, tag := range metaTagRegex.FindAll(page, -1) {
+		var key, content string
+		for _, m := range attributeRegex.FindAllSubmatch(tag, -1) {
+			value := string(m[2]) + string(m[3]) + string(m[4])
+			switch strings.ToLower(string(m[1])) {
+			case "property", "name":
+				key = strings.ToLower(value)
+			case "content":
+				content = value
+			}
+		}
+		if _, ok := tags[key]; key != "" && !ok {
+			tags[key] = strings.TrimSpace(html.UnescapeString(content))
+		}
+	}
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if tags[key] != "" {
+				return tags[key]
+			}
+		}
+		return ""
+	}
+
+	meta := pageMetadata{
+		title:       first("og:title", "twitter:title"),
+		description: first("og:description", "twitter:description", "description"),
+		image:       first("og:image", "og:image:url", "og:image:secure_url", "twitter:image"),
+	}
+	if meta.title == "" {
+		if m := titleTagRegex.FindSubmatch(page); m != nil {
+			meta.title = strings.TrimSpace(html.UnescapeString(string(m[1])))
+		}
+	}
+	return meta
+}
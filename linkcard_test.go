@@ -0,0 +1,242 @@
+package ltbsky
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestParsePageMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want pageMetadata
+	}{
+		{
+			name: "OpenGraph",
+			page: `<html><head>
+				<meta property="og:title" content="The Go Blog">
+				<meta property="og:description" content="News &amp; updates">
+				<meta property="og:image" content="/images/go.png">
+				</head></html>`,
+			want: pageMetadata{title: "The Go Blog", description: "News & updates", image: "/images/go.png"},
+		},
+		{
+			name: "Attributes in any order and quoting",
+			page: `<META content='Reversed' property='og:title' />
+				<meta content=Unquoted name=og:description>`,
+			want: pageMetadata{title: "Reversed", description: "Unquoted"},
+		},
+		{
+			name: "Fallbacks",
+			page: `<title> Page &quot;title&quot; </title>
+				<meta name="description" content="Plain description">
+				<meta name="twitter:image" content="https://example.com/card.jpg">`,
+			want: pageMetadata{title: `Page "title"`, description: "Plain description", image: "https://example.com/card.jpg"},
+		},
+		{
+			name: "OpenGraph wins over fallbacks",
+			page: `<title>Title element</title>
+				<meta name="description" content="Plain description">
+				<meta property="og:description" content="OG description">
+				<meta property="og:title" content="OG title">`,
+			want: pageMetadata{title: "OG title", description: "OG description"},
+		},
+		{
+			name: "First tag wins",
+			page: `<meta property="og:title" content="First">
+				<meta property="og:title" content="Second">`,
+			want: pageMetadata{title: "First"},
+		},
+		{
+			name: "No metadata",
+			page: `<html><body>Hello</body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parsePageMetadata([]byte(tt.page))
+			if got != tt.want {
+				t.Errorf("wanted %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+// newWebServer returns a server with a page at /page whose preview image is
+// at /thumb.png, and counts the requests it receives.
+func newWebServer(t *testing.T) (*httptest.Server, func() int) {
+	thumb, err := os.ReadFile("./test-data/bsky-go-1.png")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			_, err := w.Write([]byte(`<html><head>
+				<meta property="og:title" content="A page">
+				<meta property="og:description" content="About things">
+				<meta property="og:image" content="/thumb.png">
+				</head></html>`))
+			if err != nil {
+				return
+			}
+		case "/thumb.png":
+			w.Header().Set("Content-Type", "image/png")
+			if _, err := w.Write(thumb); err != nil {
+				return
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestAddLinkCard(t *testing.T) {
+	web, _ := newWebServer(t)
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	uri, err := client.Post(NewPostBuilder("Read this").AddLinkCard(web.URL + "/page"))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	embed := embedOf(t, pds.record(uri))
+	if embed["$type"] != "app.bsky.embed.external" {
+		t.Errorf("wanted app.bsky.embed.external, got %v", embed["$type"])
+	}
+	external, _ := embed["external"].(map[string]any)
+	if external["uri"] != web.URL+"/page" {
+		t.Errorf("wanted uri %s, got %v", web.URL+"/page", external["uri"])
+	}
+	if external["title"] != "A page" {
+		t.Errorf("wanted title 'A page', got %v", external["title"])
+	}
+	if external["description"] != "About things" {
+		t.Errorf("wanted description 'About things', got %v", external["description"])
+	}
+	thumb, _ := external["thumb"].(map[string]any)
+	if thumb["mimeType"] != "image/png" {
+		t.Errorf("wanted a PNG thumbnail, got %v", external["thumb"])
+	}
+}
+
+func TestAddLinkCardFromFirstLink(t *testing.T) {
+	web, _ := newWebServer(t)
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	pb := NewPostBuilder("Read " + web.URL + "/page and " + web.URL + "/other").AddLinkCardFromFirstLink()
+	uri, err := client.Post(pb)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	external, _ := embedOf(t, pds.record(uri))["external"].(map[string]any)
+	if external["uri"] != web.URL+"/page" {
+		t.Errorf("wanted uri %s, got %v", web.URL+"/page", external["uri"])
+	}
+}
+
+func TestAddLinkCardFromFirstLinkWithoutLink(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	uri, err := client.Post(NewPostBuilder("No links here").AddLinkCardFromFirstLink())
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if embed := pds.record(uri)["embed"]; embed != nil {
+		t.Errorf("wanted no embed, got %v", embed)
+	}
+}
+
+func TestAddLinkCardUnreachablePage(t *testing.T) {
+	web, _ := newWebServer(t)
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	uri, err := client.Post(NewPostBuilder("Read this").AddLinkCard(web.URL + "/missing"))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if embed := pds.record(uri)["embed"]; embed != nil {
+		t.Errorf("wanted no embed, got %v", embed)
+	}
+}
+
+func TestAddCustomLinkCard(t *testing.T) {
+	web, requests := newWebServer(t)
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	thumb, err := os.ReadFile("./test-data/bsky-go-1.jpg")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	pb := NewPostBuilder("Read this").AddCustomLinkCard(LinkCard{
+		URL:         web.URL + "/page",
+		Title:       "Custom title",
+		Description: "Custom description",
+		Thumb:       thumb,
+	})
+	uri, err := client.Post(pb)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	if n := requests(); n != 0 {
+		t.Errorf("wanted no requests for the page, got %d", n)
+	}
+	external, _ := embedOf(t, pds.record(uri))["external"].(map[string]any)
+	if external["title"] != "Custom title" {
+		t.Errorf("wanted title 'Custom title', got %v", external["title"])
+	}
+	if external["description"] != "Custom description" {
+		t.Errorf("wanted description 'Custom description', got %v", external["description"])
+	}
+	thumbBlob, _ := external["thumb"].(map[string]any)
+	if thumbBlob["mimeType"] != "image/jpeg" {
+		t.Errorf("wanted a JPEG thumbnail, got %v", external["thumb"])
+	}
+}
+
+func TestLinkCardWithQuote(t *testing.T) {
+	pds := newFakePDS(t)
+	quotedURI := "at://did:plc:other/app.bsky.feed.post/3kquoted"
+	pds.seed(quotedURI, map[string]any{"$type": "app.bsky.feed.post", "text": "Quoted"})
+	client := pds.newClient(t)
+
+	pb := NewPostBuilder("Both").
+		Quote(quotedURI).
+		AddCustomLinkCard(LinkCard{URL: "https://go.dev", Title: "Go"})
+	uri, err := client.Post(pb)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	embed := embedOf(t, pds.record(uri))
+	if embed["$type"] != "app.bsky.embed.recordWithMedia" {
+		t.Errorf("wanted app.bsky.embed.recordWithMedia, got %v", embed["$type"])
+	}
+	media, _ := embed["media"].(map[string]any)
+	if media["$type"] != "app.bsky.embed.external" {
+		t.Errorf("wanted media of type app.bsky.embed.external, got %v", media["$type"])
+	}
+}
@@ -0,0 +1,445 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Purposes of lists.
+const (
+	// CurateList is a list of accounts for feeds and browsing.
+	CurateList = "app.bsky.graph.defs#curatelist"
+	// ModList is a list of accounts to mute or block together.
+	ModList = "app.bsky.graph.defs#modlist"
+)
+
+// Limits enforced by CreateList and UpdateList.
+const (
+	// MaxListNameGraphemes is the maximum length of a list's name.
+	MaxListNameGraphemes = 64
+	// MaxListDescriptionGraphemes is the maximum length of a list's
+	// description.
+	MaxListDescriptionGraphemes = 300
+)
+
+// ErrInvalidList is wrapped by errors from CreateList and UpdateList for
+// lists that break Bluesky's limits.
+var ErrInvalidList = errors.New("invalid list")
+
+// A List is an app.bsky.graph.list record. Avatar refers to the current
+// image; use the SetAvatar methods to replace it, or set it to nil to remove
+// it.
+type List struct {
+	Name string
+	// Purpose is CurateList or ModList.
+	Purpose     string
+	Description string
+	Avatar      *Blob
+
+	avatar *localImage
+}
+
+// SetAvatarFromPath replaces the list's avatar with the image at path. The
+// image is read and uploaded when the list is saved.
+func (l *List) SetAvatarFromPath(path string) {
+	l.avatar = &localImage{Path: path}
+}
+
+// SetAvatarFromBytes replaces the list's avatar with the image in data.
+func (l *List) SetAvatarFromBytes(data []byte) {
+	l.avatar = &localImage{Bytes: data}
+}
+
+func (l *List) validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidList}, args...)...))
+	}
+	if l.Name == "" {
+		invalid("name is empty")
+	}
+	if n := graphemeCount(l.Name); n > MaxListNameGraphemes {
+		invalid("name is %d graphemes, over the limit of %d", n, MaxListNameGraphemes)
+	}
+	if n := graphemeCount(l.Description); n > MaxListDescriptionGraphemes {
+		invalid("description is %d graphemes, over the limit of %d", n, MaxListDescriptionGraphemes)
+	}
+	if l.Purpose != CurateList && l.Purpose != ModList {
+		invalid("purpose %q is not CurateList or ModList", l.Purpose)
+	}
+	return errors.Join(errs...)
+}
+
+// listRecord is an app.bsky.graph.list record.
+type listRecord struct {
+	Type        string `json:"$type"`
+	Purpose     string `json:"purpose"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Avatar      *Blob  `json:"avatar,omitempty"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// listItemRecord is an app.bsky.graph.listitem record, which adds the
+// account with the DID in Subject to the list at List.
+type listItemRecord struct {
+	Type      string `json:"$type"`
+	Subject   string `json:"subject"`
+	List      string `json:"list"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// A ListItem is a member of a list. URI is the URI of its listitem record.
+type ListItem struct {
+	URI     string `json:"uri"`
+	Subject Actor  `json:"subject"`
+}
+
+// ListMembersOptions controls the pages returned by GetListMembers.
+type ListMembersOptions struct {
+	// Limit is the maximum number of members per page, up to 100. Zero means
+	// the server's default.
+	Limit int
+	// Cursor is the cursor returned with the previous page, or empty for the
+	// first page.
+	Cursor string
+}
+
+// ListChanges reports the DIDs that SyncList added to and removed from a
+// list.
+type ListChanges struct {
+	Added   []string
+	Removed []string
+}
+
+// CreateList creates a list and returns its URI. The list's avatar, if set,
+// is scaled to fit Bluesky's size limit and uploaded.
+func (c *Client) CreateList(ctx context.Context, list *List) (string, error) {
+	if err := list.validate(); err != nil {
+		return "", err
+	}
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error authenticating: %w", err)
+	}
+	if list.avatar != nil {
+		if list.Avatar, err = c.uploadLocalImage(ctx, token, list.avatar); err != nil {
+			return "", fmt.Errorf("error uploading list avatar: %w", err)
+		}
+	}
+	record := &listRecord{
+		Type:        "app.bsky.graph.list",
+		Purpose:     list.Purpose,
+		Name:        list.Name,
+		Description: list.Description,
+		Avatar:      list.Avatar,
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+	resp, err := c.createRecord(ctx, token, c.handle, record.Type, newTID(), record)
+	if err != nil {
+		return "", fmt.Errorf("error creating list %s: %w", list.Name, err)
+	}
+	return resp.Uri, nil
+}
+
+// UpdateList reads the list at listURI, passes it to update, and writes back
+// the result. Fields of the list record that List does not cover are kept as
+// they are. If the list changes between being read and being written,
+// UpdateList fails with an error wrapping ErrInvalidSwap.
+func (c *Client) UpdateList(ctx context.Context, listURI string, update func(*List)) error {
+	u, err := parseListURI(listURI)
+	if err != nil {
+		return err
+	}
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+	if err := c.checkListOwner(u, listURI); err != nil {
+		return err
+	}
+	original, err := c.getRecord(ctx, token, u.repo, u.collection, u.rkey)
+	if err != nil {
+		return fmt.Errorf("error fetching list %s: %w", listURI, err)
+	}
+	var current listRecord
+	if err := json.Unmarshal(original.Value, &current); err != nil {
+		return fmt.Errorf("error unmarshaling list %s: %w", listURI, err)
+	}
+
+	list := &List{
+		Name:        current.Name,
+		Purpose:     current.Purpose,
+		Description: current.Description,
+		Avatar:      current.Avatar,
+	}
+	update(list)
+	if err := list.validate(); err != nil {
+		return err
+	}
+	if list.avatar != nil {
+		if list.Avatar, err = c.uploadLocalImage(ctx, token, list.avatar); err != nil {
+			return fmt.Errorf("error uploading list avatar: %w", err)
+		}
+	}
+
+	updated, err := mergeRecord(original.Value, &listRecord{
+		Purpose:     list.Purpose,
+		Name:        list.Name,
+		Description: list.Description,
+		Avatar:      list.Avatar,
+	}, "purpose", "name", "description", "avatar")
+	if err != nil {
+		return fmt.Errorf("error updating list %s: %w", listURI, err)
+	}
+	_, err = c.putRecord(ctx, token, u.repo, u.collection, u.rkey, original.Cid, updated)
+	if err != nil {
+		return fmt.Errorf("error updating list %s: %w", listURI, err)
+	}
+	return nil
+}
+
+// AddToList adds the account with the given handle or DID to the list at
+// listURI, and returns the URI of the listitem record.
+func (c *Client) AddToList(ctx context.Context, listURI, actor string) (string, error) {
+	u, err := parseListURI(listURI)
+	if err != nil {
+		return "", err
+	}
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error authenticating: %w", err)
+	}
+	if err := c.checkListOwner(u, listURI); err != nil {
+		return "", err
+	}
+	did, err := c.resolveActor(ctx, token, actor)
+	if err != nil {
+		return "", err
+	}
+	return c.addListItem(ctx, token, listURI, did)
+}
+
+// RemoveFromList removes an account from the list at listURI. actor is
+// either the URI of the listitem record, as returned by AddToList, or the
+// handle or DID of the account. Removing an account that is not on the list
+// is not an error.
+func (c *Client) RemoveFromList(ctx context.Context, listURI, actor string) error {
+	u, err := parseListURI(listURI)
+	if err != nil {
+		return err
+	}
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+	if err := c.checkListOwner(u, listURI); err != nil {
+		return err
+	}
+	if strings.HasPrefix(actor, "at://") {
+		return c.deleteOwnRecord(ctx, token, "app.bsky.graph.listitem", actor)
+	}
+	did, err := c.resolveActor(ctx, token, actor)
+	if err != nil {
+		return err
+	}
+	// Collect the items before deleting them, so the deletes do not
+	// disturb the paging
+	var uris []string
+	for item, err := range c.listItems(ctx, token, u.repo, listURI) {
+		if err != nil {
+			return err
+		}
+		if item.subject == did {
+			uris = append(uris, item.uri)
+		}
+	}
+	for _, uri := range uris {
+		if err := c.deleteOwnRecord(ctx, token, "app.bsky.graph.listitem", uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetListMembers returns a page of the members of the list at listURI, and
+// the cursor of the next page. The cursor is empty after the last page. The
+// list can belong to any account.
+func (c *Client) GetListMembers(ctx context.Context, listURI string, opts ListMembersOptions) ([]ListItem, string, error) {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("error authenticating: %w", err)
+	}
+	params := url.Values{"list": {listURI}}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		params.Set("cursor", opts.Cursor)
+	}
+	var resp struct {
+		Items  []ListItem `json:"items"`
+		Cursor string     `json:"cursor"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "app.bsky.graph.getList",
+		params: params,
+		token:  token,
+	}, &resp)
+	if err != nil {
+		return nil, "", fmt.Errorf("error fetching members of list %s: %w", listURI, err)
+	}
+	return resp.Items, resp.Cursor, nil
+}
+
+// IterListMembers returns an iterator over the members of the list at
+// listURI, starting at opts.Cursor and fetching pages as needed. If a page
+// cannot be fetched, the iterator yields the error and stops.
+func (c *Client) IterListMembers(ctx context.Context, listURI string, opts ListMembersOptions) iter.Seq2[ListItem, error] {
+	return paginate(opts.Cursor, func(cursor string) ([]ListItem, string, error) {
+		opts.Cursor = cursor
+		return c.GetListMembers(ctx, listURI, opts)
+	})
+}
+
+// SyncList makes the members of the list at listURI match desired, a list of
+// handles or DIDs. It adds the missing accounts and removes the others,
+// leaving the rest of the list untouched. The current members are read from
+// the user's repo rather than the app view, so changes made moments before
+// are taken into account.
+//
+// If a change fails, SyncList returns the changes made so far along with the
+// error; calling it again picks up where it stopped.
+func (c *Client) SyncList(ctx context.Context, listURI string, desired []string) (*ListChanges, error) {
+	u, err := parseListURI(listURI)
+	if err != nil {
+		return nil, err
+	}
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error authenticating: %w", err)
+	}
+	if err := c.checkListOwner(u, listURI); err != nil {
+		return nil, err
+	}
+	want := make(map[string]bool)
+	var dids []string
+	for _, actor := range desired {
+		did, err := c.resolveActor(ctx, token, actor)
+		if err != nil {
+			return nil, err
+		}
+		if !want[did] {
+			want[did] = true
+			dids = append(dids, did)
+		}
+	}
+
+	// Find the members to keep, and the items to remove, including
+	// duplicates
+	have := make(map[string]bool)
+	var stale []listItem
+	for item, err := range c.listItems(ctx, token, u.repo, listURI) {
+		if err != nil {
+			return nil, err
+		}
+		if !want[item.subject] || have[item.subject] {
+			stale = append(stale, item)
+			continue
+		}
+		have[item.subject] = true
+	}
+
+	changes := &ListChanges{}
+	for _, did := range dids {
+		if have[did] {
+			continue
+		}
+		if _, err := c.addListItem(ctx, token, listURI, did); err != nil {
+			return changes, err
+		}
+		changes.Added = append(changes.Added, did)
+	}
+	for _, item := range stale {
+		if err := c.deleteOwnRecord(ctx, token, "app.bsky.graph.listitem", item.uri); err != nil {
+			return changes, err
+		}
+		if !want[item.subject] {
+			changes.Removed = append(changes.Removed, item.subject)
+		}
+	}
+	return changes, nil
+}
+
+func (c *Client) addListItem(ctx context.Context, token, listURI, did string) (string, error) {
+	record := &listItemRecord{
+		Type:      "app.bsky.graph.listitem",
+		Subject:   did,
+		List:      listURI,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	resp, err := c.createRecord(ctx, token, c.handle, record.Type, newTID(), record)
+	if err != nil {
+		return "", fmt.Errorf("error adding %s to list %s: %w", did, listURI, err)
+	}
+	return resp.Uri, nil
+}
+
+// listItem is a listitem record found in a repo.
+type listItem struct {
+	uri     string
+	subject string
+}
+
+// listItems returns an iterator over the listitem records in repo that
+// belong to the list at listURI.
+func (c *Client) listItems(ctx context.Context, token, repo, listURI string) iter.Seq2[listItem, error] {
+	return func(yield func(listItem, error) bool) {
+		records := paginate("", func(cursor string) ([]recordResponse, string, error) {
+			return c.listRecords(ctx, token, repo, "app.bsky.graph.listitem", cursor)
+		})
+		for r, err := range records {
+			if err != nil {
+				yield(listItem{}, fmt.Errorf("error listing items of list %s: %w", listURI, err))
+				return
+			}
+			var record listItemRecord
+			if err := json.Unmarshal(r.Value, &record); err != nil || record.List != listURI {
+				continue
+			}
+			if !yield(listItem{uri: r.Uri, subject: record.Subject}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// parseListURI parses the URI of a list record.
+func parseListURI(listURI string) (*atURI, error) {
+	u, err := parseATURI(listURI)
+	if err != nil {
+		return nil, err
+	}
+	if u.collection != "app.bsky.graph.list" {
+		return nil, fmt.Errorf("%s is not a list", listURI)
+	}
+	return u, nil
+}
+
+// checkListOwner returns an error unless the list at u belongs to the
+// logged-in user. List items live in the repo of the list's owner, so the
+// user can only edit their own lists.
+func (c *Client) checkListOwner(u *atURI, listURI string) error {
+	if u.repo != c.sessionDid() && u.repo != c.handle {
+		return fmt.Errorf("list %s belongs to another account", listURI)
+	}
+	return nil
+}
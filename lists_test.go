@@ -0,0 +1,231 @@
+package ltbsky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// listMembers returns the subjects of the listitem records of the list at
+// listURI.
+func listMembers(pds *fakePDS, listURI string) []string {
+	var members []string
+	for _, uri := range pds.uris("app.bsky.graph.listitem") {
+		if item := pds.record(uri); item["list"] == listURI {
+			members = append(members, item["subject"].(string))
+		}
+	}
+	slices.Sort(members)
+	return members
+}
+
+func TestCreateList(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	list := &List{Name: "Spam", Purpose: ModList, Description: "Accounts that post spam"}
+	list.SetAvatarFromPath("./test-data/bsky-go-1.jpg")
+	uri, err := client.CreateList(context.Background(), list)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	record := pds.record(uri)
+	if record["$type"] != "app.bsky.graph.list" || record["name"] != "Spam" || record["purpose"] != ModList {
+		t.Errorf("wanted a modlist named Spam, got %v", record)
+	}
+	if record["avatar"] == nil || record["createdAt"] == nil {
+		t.Errorf("wanted an avatar and createdAt, got %v", record)
+	}
+}
+
+func TestCreateListInvalid(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	_, err := client.CreateList(context.Background(), &List{Name: strings.Repeat("a", MaxListNameGraphemes+1), Purpose: "reference"})
+	if !errors.Is(err, ErrInvalidList) {
+		t.Errorf("wanted ErrInvalidList, got %v", err)
+	}
+	if got := strings.Count(err.Error(), ErrInvalidList.Error()); got != 2 {
+		t.Errorf("wanted 2 problems, got %d in %v", got, err)
+	}
+	if n := pds.count("com.atproto.repo.createRecord"); n != 0 {
+		t.Errorf("wanted no createRecord requests, got %d", n)
+	}
+}
+
+func TestUpdateList(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := "at://did:plc:test/app.bsky.graph.list/3klist"
+	pds.seed(uri, map[string]any{
+		"$type":     "app.bsky.graph.list",
+		"name":      "Spam",
+		"purpose":   ModList,
+		"avatar":    map[string]any{"$type": "blob", "ref": map[string]string{"$link": "bafyold"}, "mimeType": "image/png", "size": 100},
+		"createdAt": "2024-01-02T03:04:05Z",
+	})
+	client := pds.newClient(t)
+
+	err := client.UpdateList(context.Background(), uri, func(l *List) {
+		if l.Name != "Spam" || l.Purpose != ModList {
+			t.Errorf("wanted the current list, got %+v", l)
+		}
+		l.Purpose = CurateList
+		l.Description = "Curated"
+		l.Avatar = nil
+	})
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	record := pds.record(uri)
+	if record["purpose"] != CurateList || record["description"] != "Curated" || record["name"] != "Spam" {
+		t.Errorf("wanted an updated curatelist named Spam, got %v", record)
+	}
+	if record["avatar"] != nil || record["createdAt"] != "2024-01-02T03:04:05Z" {
+		t.Errorf("wanted no avatar and the original createdAt, got %v", record)
+	}
+
+	if err := client.UpdateList(context.Background(), "at://did:plc:test/app.bsky.feed.post/3kpost", func(l *List) {}); err == nil {
+		t.Error("wanted an error for a post URI, got nil")
+	}
+}
+
+func TestAddAndRemoveFromList(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	ctx := context.Background()
+	listURI := "at://did:plc:test/app.bsky.graph.list/3klist"
+	otherURI := "at://did:plc:test/app.bsky.graph.list/3kother"
+
+	itemURI, err := client.AddToList(ctx, listURI, "alice.bsky.social")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	for _, actor := range []string{"did:plc:bob", "did:plc:carol", "did:plc:bob"} {
+		if _, err := client.AddToList(ctx, listURI, actor); err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+	}
+	if _, err := client.AddToList(ctx, otherURI, "did:plc:bob"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	if err := client.RemoveFromList(ctx, listURI, itemURI); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if err := client.RemoveFromList(ctx, listURI, "did:plc:bob"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if err := client.RemoveFromList(ctx, listURI, "did:plc:nobody"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if got := fmt.Sprint(listMembers(pds, listURI)); got != "[did:plc:carol]" {
+		t.Errorf("wanted [did:plc:carol], got %s", got)
+	}
+	if got := fmt.Sprint(listMembers(pds, otherURI)); got != "[did:plc:bob]" {
+		t.Errorf("wanted the other list untouched, got %s", got)
+	}
+}
+
+func TestIterListMembers(t *testing.T) {
+	pds := newFakePDS(t)
+	listURI := "at://did:plc:alice/app.bsky.graph.list/3klist"
+	pds.on("app.bsky.graph.getList", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("list") != listURI {
+			t.Errorf("wanted list %s, got %s", listURI, q.Get("list"))
+		}
+		item := func(name string) map[string]any {
+			return map[string]any{
+				"uri":     "at://did:plc:alice/app.bsky.graph.listitem/" + name,
+				"subject": map[string]string{"did": "did:plc:" + name, "handle": name + ".bsky.social"},
+			}
+		}
+		resp := map[string]any{"items": []map[string]any{item("carol")}}
+		if q.Get("cursor") == "" {
+			resp = map[string]any{"items": []map[string]any{item("alice"), item("bob")}, "cursor": "2"}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+	client := pds.newClient(t)
+
+	var handles []string
+	for item, err := range client.IterListMembers(context.Background(), listURI, ListMembersOptions{Limit: 2}) {
+		if err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+		handles = append(handles, item.Subject.Handle)
+	}
+	if got := fmt.Sprint(handles); got != "[alice.bsky.social bob.bsky.social carol.bsky.social]" {
+		t.Errorf("wanted alice, bob, and carol, got %s", got)
+	}
+}
+
+func TestSyncList(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	ctx := context.Background()
+	listURI := "at://did:plc:test/app.bsky.graph.list/3klist"
+	for _, did := range []string{"did:plc:a", "did:plc:b", "did:plc:c", "did:plc:b"} {
+		if _, err := client.AddToList(ctx, listURI, did); err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+	}
+	creates := pds.count("com.atproto.repo.createRecord")
+
+	changes, err := client.SyncList(ctx, listURI, []string{"did:plc:b", "did:plc:c", "d.example.com", "did:plc:c"})
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if fmt.Sprint(changes.Added) != "[did:plc:d-example-com]" || fmt.Sprint(changes.Removed) != "[did:plc:a]" {
+		t.Errorf("wanted d.example.com added and did:plc:a removed, got %+v", changes)
+	}
+	if got := fmt.Sprint(listMembers(pds, listURI)); got != "[did:plc:b did:plc:c did:plc:d-example-com]" {
+		t.Errorf("wanted b, c, and d.example.com, got %s", got)
+	}
+	if n := pds.count("com.atproto.repo.createRecord") - creates; n != 1 {
+		t.Errorf("wanted 1 createRecord request, got %d", n)
+	}
+
+	// Nothing left to change
+	deletes := pds.count("com.atproto.repo.deleteRecord")
+	changes, err = client.SyncList(ctx, listURI, []string{"did:plc:b", "did:plc:c", "did:plc:d-example-com"})
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(changes.Added) != 0 || len(changes.Removed) != 0 {
+		t.Errorf("wanted no changes, got %+v", changes)
+	}
+	if n := pds.count("com.atproto.repo.deleteRecord") - deletes; n != 0 {
+		t.Errorf("wanted no deleteRecord requests, got %d", n)
+	}
+}
+
+func TestListOfAnotherAccount(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	ctx := context.Background()
+	listURI := "at://did:plc:other/app.bsky.graph.list/3klist"
+
+	if _, err := client.AddToList(ctx, listURI, "did:plc:bob"); err == nil {
+		t.Error("wanted an error adding to another account's list, got nil")
+	}
+	if err := client.RemoveFromList(ctx, listURI, "did:plc:bob"); err == nil {
+		t.Error("wanted an error removing from another account's list, got nil")
+	}
+	if _, err := client.SyncList(ctx, listURI, []string{"did:plc:bob"}); err == nil {
+		t.Error("wanted an error syncing another account's list, got nil")
+	}
+	if err := client.UpdateList(ctx, listURI, func(*List) {}); err == nil {
+		t.Error("wanted an error updating another account's list, got nil")
+	}
+	for _, nsid := range []string{"com.atproto.repo.listRecords", "com.atproto.repo.createRecord", "com.atproto.repo.deleteRecord"} {
+		if n := pds.count(nsid); n != 0 {
+			t.Errorf("wanted no %s requests, got %d", nsid, n)
+		}
+	}
+}
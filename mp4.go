@@ -0,0 +1,132 @@
+package ltbsky
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// mp4Dimensions returns the display width and height of the first video track
+// in an MP4 or QuickTime file, read from its track header (tkhd) box. Tracks
+// rotated by 90 or 270 degrees have their width and height swapped.
+func mp4Dimensions(data []byte) (width, height int, err error) {
+	moov, err := findBox(data, "moov")
+	if err != nil {
+		return 0, 0, err
+	}
+	for {
+		trak, rest, err := nextBox(moov, "trak")
+		if err != nil {
+			break
+		}
+		moov = rest
+		tkhd, err := findBox(trak, "tkhd")
+		if err != nil {
+			continue
+		}
+		width, height, err := tkhdDimensions(tkhd)
+		if err != nil {
+			return 0, 0, err
+		}
+		if width > 0 && height > 0 {
+			return width, height, nil
+		}
+		// Audio tracks have no dimensions
+	}
+	return 0, 0, errors.New("no video track found")
+}
+
+// mp4Duration returns the length of an MP4 or QuickTime file, read from its
+// movie header (mvhd) box.
+func mp4Duration(data []byte) (time.Duration, error) {
+	moov, err := findBox(data, "moov")
+	if err != nil {
+		return 0, err
+	}
+	mvhd, err := findBox(moov, "mvhd")
+	if err != nil {
+		return 0, err
+	}
+	if len(mvhd) < 1 {
+		return 0, errors.New("mvhd box is empty")
+	}
+	// The version decides the size of the times before the time scale
+	var timescale, duration uint64
+	switch {
+	case mvhd[0] == 1 && len(mvhd) >= 4+16+12:
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[20:]))
+		duration = binary.BigEndian.Uint64(mvhd[24:])
+	case mvhd[0] == 0 && len(mvhd) >= 4+8+8:
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[12:]))
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:]))
+	default:
+		return 0, errors.New("mvhd box is too short")
+	}
+	if timescale == 0 {
+		return 0, errors.New("mvhd box has no time scale")
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), nil
+}
+
+// tkhdDimensions reads the dimensions from the payload of a tkhd box.
+func tkhdDimensions(tkhd []byte) (width, height int, err error) {
+	if len(tkhd) < 1 {
+		return 0, 0, errors.New("tkhd box is empty")
+	}
+	// The version decides the size of the times before the matrix
+	matrix := 4 + 20 + 16
+	if tkhd[0] == 1 {
+		matrix = 4 + 32 + 16
+	}
+	if len(tkhd) < matrix+36+8 {
+		return 0, 0, errors.New("tkhd box is too short")
+	}
+	a := int32(binary.BigEndian.Uint32(tkhd[matrix:]))
+	d := int32(binary.BigEndian.Uint32(tkhd[matrix+16:]))
+	// Width and height are 16.16 fixed-point numbers
+	width = int(binary.BigEndian.Uint32(tkhd[matrix+36:]) >> 16)
+	height = int(binary.BigEndian.Uint32(tkhd[matrix+40:]) >> 16)
+	if a == 0 && d == 0 {
+		width, height = height, width
+	}
+	return width, height, nil
+}
+
+// findBox returns the payload of the first box of type typ in data.
+func findBox(data []byte, typ string) ([]byte, error) {
+	payload, _, err := nextBox(data, typ)
+	return payload, err
+}
+
+// nextBox returns the payload of the first box of type typ in data, and the
+// data after that box.
+func nextBox(data []byte, typ string) (payload, rest []byte, err error) {
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, nil, errors.New("truncated box header")
+		}
+		size := uint64(binary.BigEndian.Uint32(data))
+		boxType := string(data[4:8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			// The box runs to the end of the file
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, nil, errors.New("truncated box header")
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			header = 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return nil, nil, fmt.Errorf("invalid size %d for %q box", size, boxType)
+		}
+		if boxType == typ {
+			return data[header:size], data[size:], nil
+		}
+		data = data[size:]
+	}
+	return nil, nil, fmt.Errorf("no %q box found", typ)
+}
@@ -0,0 +1,185 @@
+package ltbsky
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// box returns an MP4 box of type typ holding the given payloads.
+func box(typ string, payloads ...[]byte) []byte {
+	size := 8
+	for _, p := range payloads {
+		size += len(p)
+	}
+	b := binary.BigEndian.AppendUint32(nil, uint32(size))
+	b = append(b, typ...)
+	for _, p := range payloads {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// tkhd returns a track header box of the given version for a track of the
+// given size, rotated by 90 degrees if rotated is set.
+func tkhd(version byte, width, height int, rotated bool) []byte {
+	p := []byte{version, 0, 0, 7}
+	if version == 1 {
+		p = append(p, make([]byte, 32)...)
+	} else {
+		p = append(p, make([]byte, 20)...)
+	}
+	p = append(p, make([]byte, 16)...)
+	matrix := []uint32{0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000}
+	if rotated {
+		matrix = []uint32{0, 0x10000, 0, 0xffff0000, 0, 0, 0, 0, 0x40000000}
+	}
+	for _, v := range matrix {
+		p = binary.BigEndian.AppendUint32(p, v)
+	}
+	p = binary.BigEndian.AppendUint32(p, uint32(width)<<16)
+	p = binary.BigEndian.AppendUint32(p, uint32(height)<<16)
+	return box("tkhd", p)
+}
+
+// mvhd returns a movie header box of the given version for a movie of the
+// given length.
+func mvhd(version byte, timescale uint32, duration uint64) []byte {
+	p := []byte{version, 0, 0, 0}
+	if version == 1 {
+		p = append(p, make([]byte, 16)...)
+		p = binary.BigEndian.AppendUint32(p, timescale)
+		p = binary.BigEndian.AppendUint64(p, duration)
+	} else {
+		p = append(p, make([]byte, 8)...)
+		p = binary.BigEndian.AppendUint32(p, timescale)
+		p = binary.BigEndian.AppendUint32(p, uint32(duration))
+	}
+	return box("mvhd", append(p, make([]byte, 80)...))
+}
+
+// makeMP4 returns a minimal 10-second MP4 file with an audio track and a
+// video track of the given size.
+func makeMP4(width, height int) []byte {
+	return makeMP4WithDuration(width, height, 10*time.Second)
+}
+
+// makeMP4WithDuration is like makeMP4 for a video of the given length.
+func makeMP4WithDuration(width, height int, d time.Duration) []byte {
+	return append(box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2mp41")),
+		box("moov",
+			mvhd(0, 1000, uint64(d.Milliseconds())),
+			box("trak", tkhd(0, 0, 0, false), box("mdia")),
+			box("trak", tkhd(0, width, height, false), box("mdia")),
+		)...)
+}
+
+func TestMP4Dimensions(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantWidth  int
+		wantHeight int
+		wantErr    bool
+	}{
+		{
+			name:       "Landscape",
+			data:       makeMP4(1920, 1080),
+			wantWidth:  1920,
+			wantHeight: 1080,
+		},
+		{
+			name:       "Rotated",
+			data:       box("moov", box("trak", tkhd(0, 1920, 1080, true))),
+			wantWidth:  1080,
+			wantHeight: 1920,
+		},
+		{
+			name:       "Version 1 track header",
+			data:       box("moov", box("trak", tkhd(1, 640, 480, false))),
+			wantWidth:  640,
+			wantHeight: 480,
+		},
+		{
+			name:       "Box running to the end of the file",
+			data:       append([]byte{0, 0, 0, 0}, box("moov", box("trak", tkhd(0, 720, 1280, false)))[4:]...),
+			wantWidth:  720,
+			wantHeight: 1280,
+		},
+		{
+			name:    "Audio only",
+			data:    box("moov", box("trak", tkhd(0, 0, 0, false))),
+			wantErr: true,
+		},
+		{
+			name:    "Not an MP4 file",
+			data:    []byte("GIF89a"),
+			wantErr: true,
+		},
+		{
+			name:    "Truncated",
+			data:    makeMP4(1920, 1080)[:60],
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, err := mp4Dimensions(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("wanted error, got %dx%d", width, height)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("wanted no error, got %v", err)
+			}
+			if width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("wanted %dx%d, got %dx%d", tt.wantWidth, tt.wantHeight, width, height)
+			}
+		})
+	}
+}
+
+func TestMP4Duration(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    time.Duration
+		wantErr bool
+	}{
+		{
+			name: "Version 0 movie header",
+			data: box("moov", mvhd(0, 600, 600*90+300)),
+			want: 90*time.Second + 500*time.Millisecond,
+		},
+		{
+			name: "Version 1 movie header",
+			data: box("moov", mvhd(1, 1000, 4*60*1000)),
+			want: 4 * time.Minute,
+		},
+		{
+			name:    "No time scale",
+			data:    box("moov", mvhd(0, 0, 100)),
+			wantErr: true,
+		},
+		{
+			name:    "No movie header",
+			data:    box("moov", box("trak")),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mp4Duration(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wanted error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("wanted %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
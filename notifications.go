@@ -0,0 +1,360 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Reasons for notifications.
+const (
+	NotificationLike    = "like"
+	NotificationRepost  = "repost"
+	NotificationFollow  = "follow"
+	NotificationMention = "mention"
+	NotificationReply   = "reply"
+	NotificationQuote   = "quote"
+)
+
+// A Notification tells the logged-in user that another account interacted
+// with them. Record is the record that caused it, such as the reply post or
+// the like.
+type Notification struct {
+	URI    string `json:"uri"`
+	CID    string `json:"cid"`
+	Author Actor  `json:"author"`
+	// Reason is why the notification was sent, such as NotificationMention.
+	Reason string `json:"reason"`
+	// ReasonSubject is the URI of the post that was liked, reposted, or
+	// quoted, if any.
+	ReasonSubject string          `json:"reasonSubject,omitempty"`
+	Record        json.RawMessage `json:"record"`
+	IsRead        bool            `json:"isRead"`
+	IndexedAt     time.Time       `json:"indexedAt"`
+}
+
+// Post decodes the notification's record as a post. It is useful for
+// mentions, replies, and quotes.
+func (n *Notification) Post() (*PostRecord, error) {
+	var post PostRecord
+	if err := json.Unmarshal(n.Record, &post); err != nil {
+		return nil, fmt.Errorf("error unmarshaling post %s: %w", n.URI, err)
+	}
+	if post.Type != "app.bsky.feed.post" {
+		return nil, fmt.Errorf("%s is a %s record, not a post", n.URI, post.Type)
+	}
+	return &post, nil
+}
+
+// NotificationOptions controls which notifications ListNotifications returns.
+type NotificationOptions struct {
+	// Reasons limits the notifications to those with the given reasons. Empty
+	// means all reasons.
+	Reasons []string
+	// Limit is the maximum number of notifications per page, up to 100. Zero
+	// means the server's default.
+	Limit int
+	// Cursor is the cursor returned with the previous page, or empty for the
+	// first page.
+	Cursor string
+}
+
+// ListNotifications returns a page of the logged-in user's notifications,
+// newest first, and the cursor of the next page. The cursor is empty after the
+// last page.
+func (c *Client) ListNotifications(ctx context.Context, opts NotificationOptions) ([]Notification, string, error) {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("error authenticating: %w", err)
+	}
+	params := url.Values{}
+	for _, reason := range opts.Reasons {
+		params.Add("reasons", reason)
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		params.Set("cursor", opts.Cursor)
+	}
+	var resp struct {
+		Notifications []Notification `json:"notifications"`
+		Cursor        string         `json:"cursor"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "app.bsky.notification.listNotifications",
+		params: params,
+		token:  token,
+	}, &resp)
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing notifications: %w", err)
+	}
+	return resp.Notifications, resp.Cursor, nil
+}
+
+// IterNotifications returns an iterator over the logged-in user's
+// notifications, starting at opts.Cursor and fetching pages as needed. If a
+// page cannot be fetched, the iterator yields the error and stops.
+func (c *Client) IterNotifications(ctx context.Context, opts NotificationOptions) iter.Seq2[Notification, error] {
+	return paginate(opts.Cursor, func(cursor string) ([]Notification, string, error) {
+		opts.Cursor = cursor
+		return c.ListNotifications(ctx, opts)
+	})
+}
+
+// UpdateSeen marks the logged-in user's notifications up to seenAt as read.
+func (c *Client) UpdateSeen(ctx context.Context, seenAt time.Time) error {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "POST",
+		nsid:   "app.bsky.notification.updateSeen",
+		body:   map[string]string{"seenAt": seenAt.UTC().Format(time.RFC3339Nano)},
+		token:  token,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("error updating seen notifications: %w", err)
+	}
+	return nil
+}
+
+// DefaultWatchInterval is how often a NotificationWatcher polls unless its
+// Interval is set.
+const DefaultWatchInterval = 30 * time.Second
+
+// maxWatchPages is the most pages of notifications a NotificationWatcher
+// fetches in one poll.
+const maxWatchPages = 10
+
+// A NotificationHandler handles a notification. If it returns an error, the
+// NotificationWatcher stops handling notifications until its next poll, and
+// then handles the same notification again.
+type NotificationHandler func(ctx context.Context, n *Notification) error
+
+// A NotificationWatcher polls for new notifications and passes each one to
+// the handler for its reason, oldest first. Notifications without a handler
+// are skipped.
+//
+// The watcher remembers the newest notification it has handled, and can save
+// it in a CursorStore so restarts do not replay old notifications. The first
+// time it runs, it handles the notifications that have not been read yet.
+type NotificationWatcher struct {
+	// Interval is the time between polls. Zero means DefaultWatchInterval.
+	Interval time.Duration
+	// Store, if set, keeps the watcher's position between runs.
+	Store CursorStore
+	// Key is the key of the position in Store. Empty means
+	// "notifications:" followed by the client's handle.
+	Key string
+	// MarkSeen marks notifications as read after they are handled.
+	MarkSeen bool
+
+	OnMention NotificationHandler
+	OnReply   NotificationHandler
+	OnQuote   NotificationHandler
+	OnLike    NotificationHandler
+	OnRepost  NotificationHandler
+	OnFollow  NotificationHandler
+
+	client    *Client
+	loaded    bool
+	watermark watermark
+}
+
+// watermark is the position of a NotificationWatcher: the time of the newest
+// handled notification, and the URIs of the handled notifications from that
+// time.
+type watermark struct {
+	IndexedAt time.Time `json:"indexedAt"`
+	URIs      []string  `json:"uris"`
+}
+
+// NewNotificationWatcher creates a NotificationWatcher for the client's
+// notifications. Set its handlers before calling Run or Poll.
+func (c *Client) NewNotificationWatcher() *NotificationWatcher {
+	return &NotificationWatcher{client: c}
+}
+
+// Run polls for notifications until ctx is done, and then returns ctx.Err().
+// Errors from polling, including handler errors, are logged and the next poll
+// tries again.
+func (w *NotificationWatcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	for {
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error polling notifications: %v", err)
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll fetches new notifications once and handles them. It stops at the first
+// handler error and returns it.
+func (w *NotificationWatcher) Poll(ctx context.Context) error {
+	if err := w.load(); err != nil {
+		return err
+	}
+
+	// Notifications come newest first, so page back until the ones already
+	// handled
+	var fresh []*Notification
+	found := make(map[string]bool)
+	cursor := ""
+	for range maxWatchPages {
+		page, next, err := w.client.ListNotifications(ctx, NotificationOptions{Limit: 100, Cursor: cursor})
+		if err != nil {
+			return err
+		}
+		done := false
+		for i := range page {
+			n := &page[i]
+			if w.handled(n) {
+				done = done || w.older(n)
+				continue
+			}
+			if !found[n.URI] {
+				found[n.URI] = true
+				fresh = append(fresh, n)
+			}
+		}
+		if done || next == "" || len(page) == 0 {
+			break
+		}
+		cursor = next
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	// Handle the oldest first, keeping the server's order for notifications
+	// from the same time
+	slices.Reverse(fresh)
+	slices.SortStableFunc(fresh, func(a, b *Notification) int {
+		return a.IndexedAt.Compare(b.IndexedAt)
+	})
+	for _, n := range fresh {
+		if err := w.dispatch(ctx, n); err != nil {
+			return fmt.Errorf("error handling notification %s: %w", n.URI, err)
+		}
+		w.advance(n)
+		if err := w.save(); err != nil {
+			return err
+		}
+	}
+	if w.MarkSeen {
+		if err := w.client.UpdateSeen(ctx, fresh[len(fresh)-1].IndexedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *NotificationWatcher) dispatch(ctx context.Context, n *Notification) error {
+	var h NotificationHandler
+	switch n.Reason {
+	case NotificationMention:
+		h = w.OnMention
+	case NotificationReply:
+		h = w.OnReply
+	case NotificationQuote:
+		h = w.OnQuote
+	case NotificationLike:
+		h = w.OnLike
+	case NotificationRepost:
+		h = w.OnRepost
+	case NotificationFollow:
+		h = w.OnFollow
+	}
+	if h == nil {
+		return nil
+	}
+	return h(ctx, n)
+}
+
+// handled reports whether n was handled before. Before the watcher has a
+// position, the notifications that were read count as handled.
+func (w *NotificationWatcher) handled(n *Notification) bool {
+	if w.watermark.IndexedAt.IsZero() {
+		return n.IsRead
+	}
+	if n.IndexedAt.Equal(w.watermark.IndexedAt) {
+		return slices.Contains(w.watermark.URIs, n.URI)
+	}
+	return n.IndexedAt.Before(w.watermark.IndexedAt)
+}
+
+// older reports whether n is older than the watcher's position, so every
+// notification after it has been handled too.
+func (w *NotificationWatcher) older(n *Notification) bool {
+	if w.watermark.IndexedAt.IsZero() {
+		return n.IsRead
+	}
+	return n.IndexedAt.Before(w.watermark.IndexedAt)
+}
+
+func (w *NotificationWatcher) advance(n *Notification) {
+	switch {
+	case n.IndexedAt.After(w.watermark.IndexedAt):
+		w.watermark = watermark{IndexedAt: n.IndexedAt, URIs: []string{n.URI}}
+	case n.IndexedAt.Equal(w.watermark.IndexedAt):
+		w.watermark.URIs = append(w.watermark.URIs, n.URI)
+	}
+}
+
+func (w *NotificationWatcher) key() string {
+	if w.Key != "" {
+		return w.Key
+	}
+	return "notifications:" + w.client.handle
+}
+
+// load reads the watcher's position from its store the first time it is
+// called.
+func (w *NotificationWatcher) load() error {
+	if w.loaded || w.Store == nil {
+		return nil
+	}
+	cursor, err := w.Store.Load(w.key())
+	if err != nil {
+		return fmt.Errorf("error loading notification cursor: %w", err)
+	}
+	if cursor != "" {
+		if err := json.Unmarshal([]byte(cursor), &w.watermark); err != nil {
+			return fmt.Errorf("error unmarshaling notification cursor: %w", err)
+		}
+	}
+	w.loaded = true
+	return nil
+}
+
+func (w *NotificationWatcher) save() error {
+	if w.Store == nil {
+		return nil
+	}
+	b, err := json.Marshal(w.watermark)
+	if err != nil {
+		return fmt.Errorf("error marshaling notification cursor: %w", err)
+	}
+	if err := w.Store.Save(w.key(), string(b)); err != nil {
+		return fmt.Errorf("error saving notification cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeInbox serves app.bsky.notification.listNotifications from a list of
+// notifications, newest first, in pages of pageSize.
+type fakeInbox struct {
+	mu       sync.Mutex
+	items    []map[string]any
+	pageSize int
+	seenAt   string
+}
+
+// add adds a notification that is newer than the others.
+func (in *fakeInbox) add(reason, rkey string, indexedAt time.Time) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	n := map[string]any{
+		"uri":       "at://did:plc:alice/app.bsky.feed.post/" + rkey,
+		"cid":       "bafy" + rkey,
+		"author":    map[string]string{"did": "did:plc:alice", "handle": "alice.bsky.social"},
+		"reason":    reason,
+		"record":    map[string]string{"$type": "app.bsky.feed.post", "text": "Hi @test.handle", "createdAt": "2024-01-02T03:04:05Z"},
+		"isRead":    false,
+		"indexedAt": indexedAt.Format(time.RFC3339Nano),
+	}
+	in.items = append([]map[string]any{n}, in.items...)
+}
+
+// readAll marks every notification as read.
+func (in *fakeInbox) readAll() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for _, n := range in.items {
+		n["isRead"] = true
+	}
+}
+
+func handleInbox(pds *fakePDS, in *fakeInbox) {
+	pds.on("app.bsky.notification.listNotifications", func(w http.ResponseWriter, r *http.Request) {
+		in.mu.Lock()
+		defer in.mu.Unlock()
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		end := min(start+in.pageSize, len(in.items))
+		cursor := ""
+		if end < len(in.items) {
+			cursor = strconv.Itoa(end)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"notifications": in.items[start:end], "cursor": cursor})
+	})
+	pds.on("app.bsky.notification.updateSeen", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			SeenAt string `json:"seenAt"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		in.mu.Lock()
+		in.seenAt = body.SeenAt
+		in.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// recorder returns a NotificationHandler that appends the reason and rkey of
+// each notification to got.
+func recorder(got *[]string) NotificationHandler {
+	return func(ctx context.Context, n *Notification) error {
+		u, err := parseATURI(n.URI)
+		if err != nil {
+			return err
+		}
+		*got = append(*got, n.Reason+":"+u.rkey)
+		return nil
+	}
+}
+
+func TestListNotifications(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("app.bsky.notification.listNotifications", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if len(q["reasons"]) != 2 || q.Get("limit") != "50" || q.Get("cursor") != "abc" {
+			t.Errorf("wanted 2 reasons, limit 50, and cursor abc, got %v", q)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"notifications": []map[string]any{{
+				"uri":           "at://did:plc:alice/app.bsky.feed.post/3kreply",
+				"author":        map[string]string{"did": "did:plc:alice", "handle": "alice.bsky.social"},
+				"reason":        "reply",
+				"reasonSubject": "at://did:plc:test/app.bsky.feed.post/3kpost",
+				"record":        map[string]string{"$type": "app.bsky.feed.post", "text": "Nice post", "createdAt": "2024-01-02T03:04:05Z"},
+				"indexedAt":     "2024-01-02T03:04:06.123Z",
+			}},
+			"cursor": "def",
+		})
+	})
+	client := pds.newClient(t)
+
+	items, cursor, err := client.ListNotifications(context.Background(), NotificationOptions{
+		Reasons: []string{NotificationReply, NotificationMention},
+		Limit:   50,
+		Cursor:  "abc",
+	})
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(items) != 1 || cursor != "def" {
+		t.Fatalf("wanted 1 notification and cursor def, got %d and cursor '%s'", len(items), cursor)
+	}
+	n := items[0]
+	if n.Reason != NotificationReply || n.Author.Handle != "alice.bsky.social" || n.IndexedAt.IsZero() {
+		t.Errorf("wanted a reply by alice.bsky.social, got %+v", n)
+	}
+	post, err := n.Post()
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if post.Text != "Nice post" {
+		t.Errorf("wanted text 'Nice post', got '%s'", post.Text)
+	}
+}
+
+func TestNotificationPostNotAPost(t *testing.T) {
+	n := &Notification{URI: "at://did:plc:alice/app.bsky.feed.like/3klike", Record: json.RawMessage(`{"$type":"app.bsky.feed.like"}`)}
+	if _, err := n.Post(); err == nil {
+		t.Error("wanted an error, got nil")
+	}
+}
+
+func TestUpdateSeen(t *testing.T) {
+	pds := newFakePDS(t)
+	in := &fakeInbox{pageSize: 10}
+	handleInbox(pds, in)
+	client := pds.newClient(t)
+
+	seenAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := client.UpdateSeen(context.Background(), seenAt); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if in.seenAt != "2024-01-02T03:04:05Z" {
+		t.Errorf("wanted seenAt 2024-01-02T03:04:05Z, got '%s'", in.seenAt)
+	}
+}
+
+func TestNotificationWatcher(t *testing.T) {
+	pds := newFakePDS(t)
+	in := &fakeInbox{pageSize: 2}
+	handleInbox(pds, in)
+	client := pds.newClient(t)
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	in.add(NotificationLike, "old", base)
+	in.readAll()
+	in.add(NotificationMention, "a", base.Add(time.Second))
+	in.add(NotificationLike, "b", base.Add(2*time.Second))
+	in.add(NotificationReply, "c", base.Add(2*time.Second))
+	in.add(NotificationRepost, "d", base.Add(3*time.Second))
+
+	var got []string
+	w := client.NewNotificationWatcher()
+	w.MarkSeen = true
+	w.OnMention = recorder(&got)
+	w.OnReply = recorder(&got)
+	w.OnLike = recorder(&got)
+	w.OnRepost = recorder(&got)
+	w.OnQuote = recorder(&got)
+
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	want := "[mention:a like:b reply:c repost:d]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("wanted %s, got %v", want, got)
+	}
+	if in.seenAt != "2024-01-02T03:04:08Z" {
+		t.Errorf("wanted seenAt 2024-01-02T03:04:08Z, got '%s'", in.seenAt)
+	}
+
+	// A new notification with the same time as the newest handled one
+	got = nil
+	in.add(NotificationFollow, "e", base.Add(3*time.Second))
+	in.add(NotificationQuote, "f", base.Add(4*time.Second))
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	want = "[quote:f]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("wanted %s without a follow handler, got %v", want, got)
+	}
+
+	got = nil
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("wanted no notifications, got %v", got)
+	}
+}
+
+func TestNotificationWatcherStore(t *testing.T) {
+	pds := newFakePDS(t)
+	in := &fakeInbox{pageSize: 10}
+	handleInbox(pds, in)
+	client := pds.newClient(t)
+	store := NewMemoryCursorStore()
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	in.add(NotificationMention, "a", base)
+	var got []string
+	w := client.NewNotificationWatcher()
+	w.Store = store
+	w.OnMention = recorder(&got)
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if cursor, _ := store.Load("notifications:test.handle"); cursor == "" {
+		t.Error("wanted a saved cursor, got none")
+	}
+
+	// A restarted watcher skips what the first one handled, even though the
+	// notification was never marked as read
+	got = nil
+	in.add(NotificationMention, "b", base.Add(time.Second))
+	w = client.NewNotificationWatcher()
+	w.Store = store
+	w.OnMention = recorder(&got)
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if fmt.Sprint(got) != "[mention:b]" {
+		t.Errorf("wanted [mention:b], got %v", got)
+	}
+}
+
+func TestNotificationWatcherHandlerError(t *testing.T) {
+	pds := newFakePDS(t)
+	in := &fakeInbox{pageSize: 10}
+	handleInbox(pds, in)
+	client := pds.newClient(t)
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	in.add(NotificationMention, "a", base)
+	in.add(NotificationMention, "b", base.Add(time.Second))
+	in.add(NotificationMention, "c", base.Add(2*time.Second))
+
+	var got []string
+	fail := true
+	w := client.NewNotificationWatcher()
+	w.MarkSeen = true
+	w.OnMention = func(ctx context.Context, n *Notification) error {
+		if fail && n.URI == "at://did:plc:alice/app.bsky.feed.post/b" {
+			return errors.New("handler failed")
+		}
+		return recorder(&got)(ctx, n)
+	}
+
+	err := w.Poll(context.Background())
+	if err == nil || err.Error() != "error handling notification at://did:plc:alice/app.bsky.feed.post/b: handler failed" {
+		t.Errorf("wanted the handler error, got %v", err)
+	}
+	if fmt.Sprint(got) != "[mention:a]" {
+		t.Errorf("wanted [mention:a], got %v", got)
+	}
+	if in.seenAt != "" {
+		t.Errorf("wanted notifications not marked as read, got seenAt '%s'", in.seenAt)
+	}
+
+	fail = false
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if fmt.Sprint(got) != "[mention:a mention:b mention:c]" {
+		t.Errorf("wanted [mention:a mention:b mention:c], got %v", got)
+	}
+}
+
+func TestNotificationWatcherRun(t *testing.T) {
+	pds := newFakePDS(t)
+	in := &fakeInbox{pageSize: 10}
+	handleInbox(pds, in)
+	client := pds.newClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := client.NewNotificationWatcher()
+	w.Interval = time.Millisecond
+	w.OnMention = func(ctx context.Context, n *Notification) error {
+		cancel()
+		return nil
+	}
+	in.add(NotificationMention, "a", time.Now())
+
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("wanted context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wanted Run to return after cancel")
+	}
+}
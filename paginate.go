@@ -0,0 +1,30 @@
+package ltbsky
+
+import "iter"
+
+// paginate returns an iterator over the items of every page returned by
+// fetch, starting at cursor. fetch returns a page of items and the cursor of
+// the next page, which is empty after the last page. If fetch fails, the
+// iterator yields the error and stops.
+func paginate[T any](cursor string, fetch func(cursor string) ([]T, string, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			items, next, err := fetch(cursor)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			// Some endpoints keep returning a cursor after the last page
+			if next == "" || next == cursor || len(items) == 0 {
+				return
+			}
+			cursor = next
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package ltbsky
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakePDS is an in-memory stand-in for a PDS. It supports sessions, service
+// auth, handle resolution, blob uploads, and the com.atproto.repo record
+// methods. Other endpoints can be added with on.
+type fakePDS struct {
+	*httptest.Server
+	did    string
+	handle string
+
+	mu       sync.Mutex
+	records  map[string]*fakeRecord
+	order    []string
+	nextCid  int
+	blobs    int
+	calls    map[string]int
+	handlers map[string]http.HandlerFunc
+}
+
+type fakeRecord struct {
+	cid   string
+	value json.RawMessage
+}
+
+func newFakePDS(t *testing.T) *fakePDS {
+	f := &fakePDS{
+		did:      "did:plc:test",
+		handle:   "test.handle",
+		records:  make(map[string]*fakeRecord),
+		calls:    make(map[string]int),
+		handlers: make(map[string]http.HandlerFunc),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// newClient returns a Client for the fake PDS that does not retry.
+func (f *fakePDS) newClient(t *testing.T, opts ...ClientOption) *Client {
+	opts = append([]ClientOption{WithRetryPolicy(NoRetry)}, opts...)
+	client, err := NewClient(f.URL, f.handle, "test.password", opts...)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	return client
+}
+
+// on registers h for the XRPC method nsid.
+func (f *fakePDS) on(nsid string, h http.HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers["/xrpc/"+nsid] = h
+}
+
+// count returns how many times the XRPC method nsid was called.
+func (f *fakePDS) count(nsid string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls["/xrpc/"+nsid]
+}
+
+// seed stores value at uri and returns its CID.
+func (f *fakePDS) seed(uri string, value any) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	return f.store(uri, b)
+}
+
+// record returns the value stored at uri decoded into a map, or nil.
+func (f *fakePDS) record(uri string) map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, ok := f.records[uri]
+	if !ok {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(r.value, &m); err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// uris returns the URIs of all stored records in the collection, in the order
+// they were first stored.
+func (f *fakePDS) uris(collection string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var uris []string
+	for _, uri := range f.order {
+		if _, ok := f.records[uri]; ok && strings.Contains(uri, "/"+collection+"/") {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
+
+func (f *fakePDS) store(uri string, value json.RawMessage) string {
+	f.nextCid++
+	cid := fmt.Sprintf("bafytest%d", f.nextCid)
+	if _, ok := f.records[uri]; !ok {
+		f.order = append(f.order, uri)
+	}
+	f.records[uri] = &fakeRecord{cid: cid, value: value}
+	return cid
+}
+
+func (f *fakePDS) uri(repo, collection, rkey string) string {
+	if repo == f.handle {
+		repo = f.did
+	}
+	return fmt.Sprintf("at://%s/%s/%s", repo, collection, rkey)
+}
+
+func (f *fakePDS) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.calls[r.URL.Path]++
+	h, ok := f.handlers[r.URL.Path]
+	f.mu.Unlock()
+	if ok {
+		h(w, r)
+		return
+	}
+	f.serveDefault(w, r)
+}
+
+// serveDefault handles r with the built-in endpoints. Handlers registered
+// with on can call it to fall back to the default behavior.
+func (f *fakePDS) serveDefault(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/xrpc/com.atproto.server.createSession", "/xrpc/com.atproto.server.refreshSession":
+		writeJSON(w, http.StatusOK, map[string]string{
+			"accessJwt":  "test.token",
+			"refreshJwt": "test.refresh",
+			"did":        f.did,
+			"handle":     f.handle,
+		})
+	case "/xrpc/com.atproto.server.getSession":
+		writeJSON(w, http.StatusOK, map[string]any{
+			"did":    f.did,
+			"handle": f.handle,
+			"didDoc": map[string]any{
+				"id": f.did,
+				"service": []map[string]string{
+					{"id": "#atproto_pds", "type": "AtprotoPersonalDataServer", "serviceEndpoint": f.URL},
+				},
+			},
+		})
+	case "/xrpc/com.atproto.server.getServiceAuth":
+		q := r.URL.Query()
+		writeJSON(w, http.StatusOK, map[string]string{"token": "service.token:" + q.Get("aud") + ":" + q.Get("lxm")})
+	case "/xrpc/com.atproto.identity.resolveHandle":
+		handle := r.URL.Query().Get("handle")
+		writeJSON(w, http.StatusOK, map[string]string{"did": "did:plc:" + strings.ReplaceAll(handle, ".", "-")})
+	case "/xrpc/com.atproto.repo.uploadBlob":
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		f.blobs++
+		writeJSON(w, http.StatusOK, map[string]any{"blob": map[string]any{
+			"$type":    "blob",
+			"ref":      map[string]string{"$link": fmt.Sprintf("bafyblob%d", f.blobs)},
+			"mimeType": r.Header.Get("Content-Type"),
+			"size":     len(b),
+		}})
+	case "/xrpc/com.atproto.repo.getRecord":
+		q := r.URL.Query()
+		uri := f.uri(q.Get("repo"), q.Get("collection"), q.Get("rkey"))
+		rec, ok := f.records[uri]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "RecordNotFound", "Could not locate record: "+uri)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"uri": uri, "cid": rec.cid, "value": rec.value})
+	case "/xrpc/com.atproto.repo.listRecords":
+		// Pages hold two records, so tests cover paging
+		q := r.URL.Query()
+		prefix := f.uri(q.Get("repo"), q.Get("collection"), "")
+		var records []map[string]any
+		for _, uri := range f.order {
+			if rec, ok := f.records[uri]; ok && strings.HasPrefix(uri, prefix) {
+				records = append(records, map[string]any{"uri": uri, "cid": rec.cid, "value": rec.value})
+			}
+		}
+		start, _ := strconv.Atoi(q.Get("cursor"))
+		start = min(start, len(records))
+		end := min(start+2, len(records))
+		resp := map[string]any{"records": records[start:end]}
+		if end < len(records) {
+			resp["cursor"] = strconv.Itoa(end)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case "/xrpc/com.atproto.repo.createRecord", "/xrpc/com.atproto.repo.putRecord":
+		var body struct {
+			Repo       string          `json:"repo"`
+			Collection string          `json:"collection"`
+			Rkey       string          `json:"rkey"`
+			Record     json.RawMessage `json:"record"`
+			// SwapRecord is null when the record must not exist yet
+			SwapRecord json.RawMessage `json:"swapRecord"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		if body.Rkey == "" {
+			body.Rkey = newTID()
+		}
+		uri := f.uri(body.Repo, body.Collection, body.Rkey)
+		existing, exists := f.records[uri]
+		if r.URL.Path == "/xrpc/com.atproto.repo.createRecord" && exists {
+			writeError(w, http.StatusBadRequest, "InvalidRequest", "Record already exists: "+uri)
+			return
+		}
+		if len(body.SwapRecord) > 0 {
+			var swap *string
+			if err := json.Unmarshal(body.SwapRecord, &swap); err != nil {
+				writeError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+				return
+			}
+			if swap == nil && exists {
+				writeError(w, http.StatusBadRequest, "InvalidSwap", "Record already exists: "+uri)
+				return
+			}
+			if swap != nil && (!exists || existing.cid != *swap) {
+				writeError(w, http.StatusBadRequest, "InvalidSwap", "Record was at "+*swap)
+				return
+			}
+		}
+		cid := f.store(uri, body.Record)
+		writeJSON(w, http.StatusOK, map[string]string{"uri": uri, "cid": cid})
+	case "/xrpc/com.atproto.repo.applyWrites":
+		var body struct {
+			Repo   string `json:"repo"`
+			Writes []struct {
+				Type       string          `json:"$type"`
+				Collection string          `json:"collection"`
+				Rkey       string          `json:"rkey"`
+				Value      json.RawMessage `json:"value"`
+			} `json:"writes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		// Check every write before applying any, as the writes are atomic
+		for _, write := range body.Writes {
+			uri := f.uri(body.Repo, write.Collection, write.Rkey)
+			if _, exists := f.records[uri]; exists || write.Type != "com.atproto.repo.applyWrites#create" {
+				writeError(w, http.StatusBadRequest, "InvalidRequest", "Cannot apply write to "+uri)
+				return
+			}
+		}
+		var results []map[string]string
+		for _, write := range body.Writes {
+			uri := f.uri(body.Repo, write.Collection, write.Rkey)
+			cid := f.store(uri, write.Value)
+			results = append(results, map[string]string{"$type": "com.atproto.repo.applyWrites#createResult", "uri": uri, "cid": cid})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"results": results})
+	case "/xrpc/com.atproto.repo.deleteRecord":
+		var body struct {
+			Repo       string  `json:"repo"`
+			Collection string  `json:"collection"`
+			Rkey       string  `json:"rkey"`
+			SwapRecord *string `json:"swapRecord"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		uri := f.uri(body.Repo, body.Collection, body.Rkey)
+		existing, exists := f.records[uri]
+		if body.SwapRecord != nil && (!exists || existing.cid != *body.SwapRecord) {
+			writeError(w, http.StatusBadRequest, "InvalidSwap", "Record was at "+*body.SwapRecord)
+			return
+		}
+		delete(f.records, uri)
+		writeJSON(w, http.StatusOK, map[string]any{})
+	default:
+		writeError(w, http.StatusNotImplemented, "MethodNotImplemented", "Method not implemented: "+r.URL.Path)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, name, message string) {
+	writeJSON(w, status, map[string]string{"error": name, "message": message})
+}
@@ -0,0 +1,171 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// A ProfileView is an account's profile as shown to the logged-in user, with
+// URLs of its images and its counts.
+type ProfileView struct {
+	Actor
+	Banner         string `json:"banner,omitempty"`
+	FollowersCount int    `json:"followersCount"`
+	FollowsCount   int    `json:"followsCount"`
+	PostsCount     int    `json:"postsCount"`
+	// PinnedPost is the post shown at the top of the account's feed, if any.
+	PinnedPost *StrongRef `json:"pinnedPost,omitempty"`
+}
+
+// A Profile is the app.bsky.actor.profile record of the logged-in user, as
+// passed to the function given to UpdateProfile. Avatar and Banner refer to
+// the current images; use the SetAvatar and SetBanner methods to replace
+// them, or set them to nil to remove them.
+type Profile struct {
+	DisplayName string
+	Description string
+	Avatar      *Blob
+	Banner      *Blob
+	PinnedPost  *StrongRef
+
+	avatar *localImage
+	banner *localImage
+}
+
+// SetAvatarFromPath replaces the avatar with the image at path. The image is
+// read and uploaded by UpdateProfile.
+func (p *Profile) SetAvatarFromPath(path string) {
+	p.avatar = &localImage{Path: path}
+}
+
+// SetAvatarFromBytes replaces the avatar with the image in data.
+func (p *Profile) SetAvatarFromBytes(data []byte) {
+	p.avatar = &localImage{Bytes: data}
+}
+
+// SetBannerFromPath replaces the banner with the image at path. The image is
+// read and uploaded by UpdateProfile.
+func (p *Profile) SetBannerFromPath(path string) {
+	p.banner = &localImage{Path: path}
+}
+
+// SetBannerFromBytes replaces the banner with the image in data.
+func (p *Profile) SetBannerFromBytes(data []byte) {
+	p.banner = &localImage{Bytes: data}
+}
+
+// profileRecord holds the fields of a profile record that UpdateProfile
+// manages.
+type profileRecord struct {
+	Type        string     `json:"$type"`
+	DisplayName string     `json:"displayName,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Avatar      *Blob      `json:"avatar,omitempty"`
+	Banner      *Blob      `json:"banner,omitempty"`
+	PinnedPost  *StrongRef `json:"pinnedPost,omitempty"`
+}
+
+// GetProfile returns the profile of the account with the given handle or DID.
+func (c *Client) GetProfile(ctx context.Context, actor string) (*ProfileView, error) {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error authenticating: %w", err)
+	}
+	var profile ProfileView
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "app.bsky.actor.getProfile",
+		params: url.Values{"actor": {actor}},
+		token:  token,
+	}, &profile)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching profile of %s: %w", actor, err)
+	}
+	return &profile, nil
+}
+
+// UpdateProfile reads the logged-in user's profile, passes it to update, and
+// writes back the result. New avatar and banner images are scaled to fit
+// Bluesky's size limit and uploaded. Fields of the profile record that
+// Profile does not cover are kept as they are.
+//
+// If the profile changes between being read and being written, or is created
+// by someone else when there was none, UpdateProfile fails with an error
+// wrapping ErrInvalidSwap instead of overwriting the other change.
+func (c *Client) UpdateProfile(ctx context.Context, update func(*Profile)) error {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+
+	// A new account may not have a profile record yet
+	const collection, rkey = "app.bsky.actor.profile", "self"
+	original := &recordResponse{Value: json.RawMessage(`{}`)}
+	existing, err := c.getRecord(ctx, token, c.handle, collection, rkey)
+	if err == nil {
+		original = existing
+	} else if !errors.Is(err, ErrRecordNotFound) {
+		return fmt.Errorf("error fetching profile: %w", err)
+	}
+	var current profileRecord
+	if err := json.Unmarshal(original.Value, &current); err != nil {
+		return fmt.Errorf("error unmarshaling profile: %w", err)
+	}
+
+	p := &Profile{
+		DisplayName: current.DisplayName,
+		Description: current.Description,
+		Avatar:      current.Avatar,
+		Banner:      current.Banner,
+		PinnedPost:  current.PinnedPost,
+	}
+	update(p)
+	if p.avatar != nil {
+		if p.Avatar, err = c.uploadLocalImage(ctx, token, p.avatar); err != nil {
+			return fmt.Errorf("error uploading avatar: %w", err)
+		}
+	}
+	if p.banner != nil {
+		if p.Banner, err = c.uploadLocalImage(ctx, token, p.banner); err != nil {
+			return fmt.Errorf("error uploading banner: %w", err)
+		}
+	}
+
+	updated, err := mergeRecord(original.Value, &profileRecord{
+		Type:        collection,
+		DisplayName: p.DisplayName,
+		Description: p.Description,
+		Avatar:      p.Avatar,
+		Banner:      p.Banner,
+		PinnedPost:  p.PinnedPost,
+	}, "$type", "displayName", "description", "avatar", "banner", "pinnedPost")
+	if err != nil {
+		return fmt.Errorf("error updating profile: %w", err)
+	}
+	_, err = c.putRecord(ctx, token, c.handle, collection, rkey, original.Cid, updated)
+	if err != nil {
+		return fmt.Errorf("error updating profile: %w", err)
+	}
+	return nil
+}
+
+// uploadLocalImage reads img from disk if needed, scales it to fit the size
+// limit for blobs, and uploads it.
+func (c *Client) uploadLocalImage(ctx context.Context, token string, img *localImage) (*Blob, error) {
+	if img.Path != "" {
+		data, err := os.ReadFile(img.Path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading image from path %s: %w", img.Path, err)
+		}
+		img.Bytes = data
+	}
+	data, mimetype, _, err := prepareImage(img.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return c.uploadBlob(ctx, token, data, mimetype)
+}
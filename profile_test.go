@@ -0,0 +1,162 @@
+package ltbsky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestGetProfile(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("app.bsky.actor.getProfile", func(w http.ResponseWriter, r *http.Request) {
+		if actor := r.URL.Query().Get("actor"); actor != "alice.bsky.social" {
+			t.Errorf("wanted actor alice.bsky.social, got %s", actor)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"did":            "did:plc:alice",
+			"handle":         "alice.bsky.social",
+			"displayName":    "Alice",
+			"description":    "Gopher",
+			"avatar":         "https://cdn.example.com/avatar.jpg",
+			"banner":         "https://cdn.example.com/banner.jpg",
+			"followersCount": 10,
+			"followsCount":   5,
+			"postsCount":     42,
+			"viewer":         map[string]string{"following": "at://did:plc:test/app.bsky.graph.follow/3kfollow"},
+		})
+	})
+	client := pds.newClient(t)
+
+	profile, err := client.GetProfile(context.Background(), "alice.bsky.social")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if profile.Handle != "alice.bsky.social" || profile.DisplayName != "Alice" || profile.Banner == "" {
+		t.Errorf("wanted Alice's profile with a banner, got %+v", profile)
+	}
+	if profile.FollowersCount != 10 || profile.FollowsCount != 5 || profile.PostsCount != 42 {
+		t.Errorf("wanted 10 followers, 5 follows, and 42 posts, got %+v", profile)
+	}
+	if profile.Viewer == nil || profile.Viewer.Following == "" {
+		t.Errorf("wanted viewer following, got %+v", profile.Viewer)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := "at://did:plc:test/app.bsky.actor.profile/self"
+	pds.seed(uri, map[string]any{
+		"$type":       "app.bsky.actor.profile",
+		"displayName": "Test",
+		"description": "Summer bot",
+		"avatar":      map[string]any{"$type": "blob", "ref": map[string]string{"$link": "bafyold"}, "mimeType": "image/png", "size": 100},
+		"banner":      map[string]any{"$type": "blob", "ref": map[string]string{"$link": "bafybanner"}, "mimeType": "image/png", "size": 100},
+		"labels":      map[string]any{"$type": "com.atproto.label.defs#selfLabels", "values": []any{}},
+	})
+	client := pds.newClient(t)
+
+	err := client.UpdateProfile(context.Background(), func(p *Profile) {
+		if p.DisplayName != "Test" || p.Avatar == nil || p.Avatar.Ref.Link != "bafyold" {
+			t.Errorf("wanted the current profile, got %+v", p)
+		}
+		p.Description = "Winter bot"
+		p.SetAvatarFromPath("./test-data/bsky-go-1.png")
+		p.Banner = nil
+	})
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	got := pds.record(uri)
+	if got["displayName"] != "Test" || got["description"] != "Winter bot" {
+		t.Errorf("wanted display name Test and description 'Winter bot', got %v", got)
+	}
+	avatar, _ := got["avatar"].(map[string]any)
+	if avatar == nil || avatar["ref"].(map[string]any)["$link"] != "bafyblob1" {
+		t.Errorf("wanted the uploaded avatar, got %v", got["avatar"])
+	}
+	if size, _ := avatar["size"].(float64); size == 0 || size > 1_000_000 {
+		t.Errorf("wanted an avatar under 1MB, got %v bytes", avatar["size"])
+	}
+	if _, ok := got["banner"]; ok {
+		t.Errorf("wanted no banner, got %v", got["banner"])
+	}
+	if _, ok := got["labels"]; !ok {
+		t.Error("wanted labels to be kept, got none")
+	}
+}
+
+func TestUpdateProfileCreates(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	banner, err := os.ReadFile("./test-data/bsky-go-1.jpg")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	err = client.UpdateProfile(context.Background(), func(p *Profile) {
+		p.DisplayName = "New bot"
+		p.SetBannerFromBytes(banner)
+	})
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	got := pds.record("at://did:plc:test/app.bsky.actor.profile/self")
+	if got["$type"] != "app.bsky.actor.profile" || got["displayName"] != "New bot" || got["banner"] == nil {
+		t.Errorf("wanted a new profile with a banner, got %v", got)
+	}
+}
+
+func TestUpdateProfileSwap(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := "at://did:plc:test/app.bsky.actor.profile/self"
+	pds.seed(uri, map[string]any{"$type": "app.bsky.actor.profile", "displayName": "Test"})
+	client := pds.newClient(t)
+
+	err := client.UpdateProfile(context.Background(), func(p *Profile) {
+		// Someone else changes the profile in the meantime
+		pds.seed(uri, map[string]any{"$type": "app.bsky.actor.profile", "displayName": "Other"})
+		p.DisplayName = "Mine"
+	})
+	if !errors.Is(err, ErrInvalidSwap) {
+		t.Errorf("wanted ErrInvalidSwap, got %v", err)
+	}
+	if got := pds.record(uri); got["displayName"] != "Other" {
+		t.Errorf("wanted display name Other, got %v", got["displayName"])
+	}
+}
+
+func TestUpdateProfileCreateSwap(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := "at://did:plc:test/app.bsky.actor.profile/self"
+	client := pds.newClient(t)
+
+	err := client.UpdateProfile(context.Background(), func(p *Profile) {
+		// Someone else creates the profile in the meantime
+		pds.seed(uri, map[string]any{"$type": "app.bsky.actor.profile", "displayName": "Other"})
+		p.DisplayName = "Mine"
+	})
+	if !errors.Is(err, ErrInvalidSwap) {
+		t.Errorf("wanted ErrInvalidSwap, got %v", err)
+	}
+	if got := pds.record(uri); got["displayName"] != "Other" {
+		t.Errorf("wanted display name Other, got %v", got["displayName"])
+	}
+}
+
+func TestUpdateProfileMissingImage(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	err := client.UpdateProfile(context.Background(), func(p *Profile) {
+		p.SetAvatarFromPath("./test-data/missing.png")
+	})
+	if err == nil {
+		t.Fatal("wanted an error, got nil")
+	}
+	if n := pds.count("com.atproto.repo.putRecord"); n != 0 {
+		t.Errorf("wanted no putRecord requests, got %d", n)
+	}
+}
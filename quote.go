@@ -0,0 +1,52 @@
+package ltbsky
+
+import (
+	"context"
+	"fmt"
+)
+
+// Quote makes the post quote the post at atURI. If the post also has images,
+// they are shown alongside the quoted post.
+func (pb *PostBuilder) Quote(atURI string) *PostBuilder {
+	pb.quoteOf = atURI
+	pb.quote = nil
+	return pb
+}
+
+// resolveQuote fetches the quoted post to find its CID. The ref is kept in
+// the builder so it is not fetched again.
+func (c *Client) resolveQuote(ctx context.Context, token string, pb *PostBuilder) error {
+	if pb.quote != nil || pb.quoteOf == "" {
+		return nil
+	}
+	u, err := parseATURI(pb.quoteOf)
+	if err != nil {
+		return err
+	}
+	quoted, err := c.getRecord(ctx, token, u.repo, u.collection, u.rkey)
+	if err != nil {
+		return fmt.Errorf("error fetching quoted post %s: %w", pb.quoteOf, err)
+	}
+	pb.quote = &StrongRef{URI: quoted.Uri, CID: quoted.Cid}
+	return nil
+}
+
+// embedQuoteInPost embeds the quoted post in the post record. If the record
+// already embeds media, both are combined in a recordWithMedia embed.
+func embedQuoteInPost(pb *PostBuilder, pr *postRequest) {
+	if pb.quote == nil {
+		return
+	}
+	if pr.Record.Embed == nil {
+		pr.Record.Embed = &Embed{
+			Type:   "app.bsky.embed.record",
+			Record: pb.quote,
+		}
+		return
+	}
+	pr.Record.Embed = &Embed{
+		Type:   "app.bsky.embed.recordWithMedia",
+		Record: pb.quote,
+		Media:  pr.Record.Embed,
+	}
+}
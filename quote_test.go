@@ -0,0 +1,87 @@
+package ltbsky
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	pds := newFakePDS(t)
+	quotedURI := "at://did:plc:other/app.bsky.feed.post/3kquoted"
+	quotedCid := pds.seed(quotedURI, map[string]any{"$type": "app.bsky.feed.post", "text": "Quoted"})
+
+	client := pds.newClient(t)
+	uri, err := client.Post(NewPostBuilder("Look at this").Quote(quotedURI))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	embed := embedOf(t, pds.record(uri))
+	if embed["$type"] != "app.bsky.embed.record" {
+		t.Errorf("wanted app.bsky.embed.record, got %v", embed["$type"])
+	}
+	wantRef := map[string]any{"uri": quotedURI, "cid": quotedCid}
+	if !equalRef(embed["record"], wantRef) {
+		t.Errorf("wanted record %v, got %v", wantRef, embed["record"])
+	}
+}
+
+func TestQuoteWithImages(t *testing.T) {
+	pds := newFakePDS(t)
+	quotedURI := "at://did:plc:other/app.bsky.feed.post/3kquoted"
+	quotedCid := pds.seed(quotedURI, map[string]any{"$type": "app.bsky.feed.post", "text": "Quoted"})
+
+	client := pds.newClient(t)
+	pb := NewPostBuilder("Look at this").
+		Quote(quotedURI).
+		AddImageFromPath("./test-data/bsky-go-1.png", "A screenshot")
+	uri, err := client.Post(pb)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	embed := embedOf(t, pds.record(uri))
+	if embed["$type"] != "app.bsky.embed.recordWithMedia" {
+		t.Errorf("wanted app.bsky.embed.recordWithMedia, got %v", embed["$type"])
+	}
+	record, _ := embed["record"].(map[string]any)
+	if record["$type"] != "app.bsky.embed.record" {
+		t.Errorf("wanted record of type app.bsky.embed.record, got %v", record["$type"])
+	}
+	wantRef := map[string]any{"uri": quotedURI, "cid": quotedCid}
+	if !equalRef(record["record"], wantRef) {
+		t.Errorf("wanted record %v, got %v", wantRef, record["record"])
+	}
+	media, _ := embed["media"].(map[string]any)
+	if media["$type"] != "app.bsky.embed.images" {
+		t.Errorf("wanted media of type app.bsky.embed.images, got %v", media["$type"])
+	}
+	if images, _ := media["images"].([]any); len(images) != 1 {
+		t.Errorf("wanted 1 image, got %v", media["images"])
+	}
+}
+
+func TestQuoteMissingPost(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	_, err := client.Post(NewPostBuilder("Look at this").Quote("at://did:plc:other/app.bsky.feed.post/3kmissing"))
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("wanted ErrRecordNotFound, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.createRecord"); n != 0 {
+		t.Errorf("wanted no createRecord calls, got %d", n)
+	}
+}
+
+// embedOf returns the embed field of a post record.
+func embedOf(t *testing.T, record map[string]any) map[string]any {
+	t.Helper()
+	if record == nil {
+		t.Fatal("wanted a record, got nil")
+	}
+	embed, ok := record["embed"].(map[string]any)
+	if !ok {
+		t.Fatalf("wanted record to have an embed, got %v", record)
+	}
+	return embed
+}
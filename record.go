@@ -0,0 +1,294 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"reflect"
+)
+
+// recordResponse is returned by the com.atproto.repo record methods. Value is
+// only set by getRecord.
+type recordResponse struct {
+	Uri   string          `json:"uri"`
+	Cid   string          `json:"cid"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// getRecord fetches a single record from a repo.
+func (c *Client) getRecord(ctx context.Context, token, repo, collection, rkey string) (*recordResponse, error) {
+	var resp recordResponse
+	err := c.xrpc(ctx, getRecordRequest(token, repo, collection, rkey), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func getRecordRequest(token, repo, collection, rkey string) *xrpcRequest {
+	return &xrpcRequest{
+		method: "GET",
+		nsid:   "com.atproto.repo.getRecord",
+		params: url.Values{
+			"repo":       {repo},
+			"collection": {collection},
+			"rkey":       {rkey},
+		},
+		token: token,
+	}
+}
+
+// lookupRecord fetches a record once, without retrying, so a failed write
+// can check whether it took effect before being retried. It returns the
+// record, or nil if it does not exist, and whether the check succeeded.
+// Errors other than ErrRecordNotFound are logged.
+func (c *Client) lookupRecord(ctx context.Context, token, repo, collection, rkey string) (*recordResponse, bool) {
+	var existing recordResponse
+	err := c.xrpcOnce(ctx, getRecordRequest(token, repo, collection, rkey), &existing)
+	if errors.Is(err, ErrRecordNotFound) {
+		return nil, true
+	}
+	if err != nil {
+		log.Printf("Error checking for record %s/%s before retrying: %v", collection, rkey, err)
+		return nil, false
+	}
+	return &existing, true
+}
+
+// createRecord creates a record with the given rkey. Choosing the rkey on the
+// client makes retries safe: before each retry, the record is looked up so an
+// earlier attempt that succeeded, but whose response was lost, is not
+// repeated.
+func (c *Client) createRecord(ctx context.Context, token, repo, collection, rkey string, record any) (*recordResponse, error) {
+	requestBody := struct {
+		Repo       string `json:"repo"`
+		Collection string `json:"collection"`
+		Rkey       string `json:"rkey"`
+		Record     any    `json:"record"`
+	}{
+		Repo:       repo,
+		Collection: collection,
+		Rkey:       rkey,
+		Record:     record,
+	}
+	var resp recordResponse
+	err := c.xrpc(ctx, &xrpcRequest{
+		method: "POST",
+		nsid:   "com.atproto.repo.createRecord",
+		body:   requestBody,
+		token:  token,
+		beforeRetry: func(ctx context.Context, out any) bool {
+			existing, _ := c.lookupRecord(ctx, token, repo, collection, rkey)
+			if existing == nil {
+				return false
+			}
+			*out.(*recordResponse) = recordResponse{Uri: existing.Uri, Cid: existing.Cid}
+			return true
+		},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// deleteRecord deletes a record. If swapCID is set, the record is only
+// deleted if its current CID matches. Before each retry, the record is looked
+// up so an earlier attempt that succeeded is not reported as a failed swap.
+func (c *Client) deleteRecord(ctx context.Context, token, repo, collection, rkey, swapCID string) error {
+	requestBody := struct {
+		Repo       string `json:"repo"`
+		Collection string `json:"collection"`
+		Rkey       string `json:"rkey"`
+		SwapRecord string `json:"swapRecord,omitempty"`
+	}{
+		Repo:       repo,
+		Collection: collection,
+		Rkey:       rkey,
+		SwapRecord: swapCID,
+	}
+	return c.xrpc(ctx, &xrpcRequest{
+		method: "POST",
+		nsid:   "com.atproto.repo.deleteRecord",
+		body:   requestBody,
+		token:  token,
+		beforeRetry: func(ctx context.Context, out any) bool {
+			existing, ok := c.lookupRecord(ctx, token, repo, collection, rkey)
+			return ok && existing == nil
+		},
+	}, nil)
+}
+
+// putRecord writes a record, replacing the record with the same rkey only if
+// its current CID is swapCID. An empty swapCID means there must be no record
+// with that rkey yet. Before each retry, the record is looked up so an earlier
+// attempt that succeeded is not reported as a failed swap.
+func (c *Client) putRecord(ctx context.Context, token, repo, collection, rkey, swapCID string, record any) (*recordResponse, error) {
+	requestBody := struct {
+		Repo       string  `json:"repo"`
+		Collection string  `json:"collection"`
+		Rkey       string  `json:"rkey"`
+		Record     any     `json:"record"`
+		SwapRecord *string `json:"swapRecord"`
+	}{
+		Repo:       repo,
+		Collection: collection,
+		Rkey:       rkey,
+		Record:     record,
+	}
+	// A null swapRecord only allows creating the record
+	if swapCID != "" {
+		requestBody.SwapRecord = &swapCID
+	}
+	var resp recordResponse
+	err := c.xrpc(ctx, &xrpcRequest{
+		method: "POST",
+		nsid:   "com.atproto.repo.putRecord",
+		body:   requestBody,
+		token:  token,
+		beforeRetry: func(ctx context.Context, out any) bool {
+			existing, _ := c.lookupRecord(ctx, token, repo, collection, rkey)
+			if existing == nil || existing.Cid == swapCID || !sameJSON(existing.Value, record) {
+				return false
+			}
+			*out.(*recordResponse) = recordResponse{Uri: existing.Uri, Cid: existing.Cid}
+			return true
+		},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// sameJSON reports whether stored and record have the same JSON encoding,
+// ignoring formatting and key order.
+func sameJSON(stored json.RawMessage, record any) bool {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return false
+	}
+	var x, y any
+	if json.Unmarshal(stored, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// listRecords returns a page of up to 100 records in the collection of repo,
+// and the cursor of the next page.
+func (c *Client) listRecords(ctx context.Context, token, repo, collection, cursor string) ([]recordResponse, string, error) {
+	params := url.Values{
+		"repo":       {repo},
+		"collection": {collection},
+		"limit":      {"100"},
+	}
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+	var resp struct {
+		Records []recordResponse `json:"records"`
+		Cursor  string           `json:"cursor"`
+	}
+	err := c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "com.atproto.repo.listRecords",
+		params: params,
+		token:  token,
+	}, &resp)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.Records, resp.Cursor, nil
+}
+
+// mergeRecord applies the named fields of updated to the original record.
+// Named fields missing from updated are removed; other fields keep their
+// original values.
+func mergeRecord(original json.RawMessage, updated any, fields ...string) (map[string]json.RawMessage, error) {
+	merged := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(original, &merged); err != nil {
+		return nil, fmt.Errorf("error unmarshaling original record: %w", err)
+	}
+	b, err := json.Marshal(updated)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling record: %w", err)
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("error unmarshaling record: %w", err)
+	}
+	for _, name := range fields {
+		delete(merged, name)
+		if value, ok := values[name]; ok {
+			merged[name] = value
+		}
+	}
+	return merged, nil
+}
+
+// recordWrite is a create operation of a com.atproto.repo.applyWrites
+// request.
+type recordWrite struct {
+	Type       string `json:"$type"`
+	Collection string `json:"collection"`
+	Rkey       string `json:"rkey"`
+	Value      any    `json:"value"`
+}
+
+func newCreateWrite(collection, rkey string, value any) recordWrite {
+	return recordWrite{
+		Type:       "com.atproto.repo.applyWrites#create",
+		Collection: collection,
+		Rkey:       rkey,
+		Value:      value,
+	}
+}
+
+type applyWritesResponse struct {
+	Results []recordResponse `json:"results"`
+}
+
+// createRecords creates several records in one commit, so either all of
+// them are created or none are. It returns the URI and CID of each record,
+// in order.
+func (c *Client) createRecords(ctx context.Context, token, repo string, writes []recordWrite) ([]recordResponse, error) {
+	requestBody := struct {
+		Repo   string        `json:"repo"`
+		Writes []recordWrite `json:"writes"`
+	}{
+		Repo:   repo,
+		Writes: writes,
+	}
+	var resp applyWritesResponse
+	err := c.xrpc(ctx, &xrpcRequest{
+		method: "POST",
+		nsid:   "com.atproto.repo.applyWrites",
+		body:   requestBody,
+		token:  token,
+		beforeRetry: func(ctx context.Context, out any) bool {
+			// The writes are applied together, so if the records exist, the
+			// previous attempt succeeded
+			var results []recordResponse
+			for _, w := range writes {
+				existing, _ := c.lookupRecord(ctx, token, repo, w.Collection, w.Rkey)
+				if existing == nil {
+					return false
+				}
+				results = append(results, recordResponse{Uri: existing.Uri, Cid: existing.Cid})
+			}
+			out.(*applyWritesResponse).Results = results
+			return true
+		},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(writes) {
+		return nil, fmt.Errorf("expected %d results from applyWrites, got %d", len(writes), len(resp.Results))
+	}
+	return resp.Results, nil
+}
@@ -0,0 +1,68 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// A StrongRef identifies a specific version of a record by its AT-URI and
+// content hash (CID).
+type StrongRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+// A ReplyRef places a post in a thread, under its parent post.
+type ReplyRef struct {
+	Root   StrongRef `json:"root"`
+	Parent StrongRef `json:"parent"`
+}
+
+// ReplyTo makes the post a reply to the post at atURI. The thread root is
+// worked out from the parent post when the post is sent.
+func (pb *PostBuilder) ReplyTo(atURI string) *PostBuilder {
+	pb.replyTo = atURI
+	pb.reply = nil
+	return pb
+}
+
+// resolveReply fills in the reply refs of the post record, fetching the
+// parent post to find the thread root. The refs are kept in the builder so
+// they are not fetched again.
+func (c *Client) resolveReply(ctx context.Context, token string, pb *PostBuilder, pr *postRequest) error {
+	if pb.reply != nil {
+		pr.Record.Reply = pb.reply
+		return nil
+	}
+	if pb.replyTo == "" {
+		return nil
+	}
+	u, err := parseATURI(pb.replyTo)
+	if err != nil {
+		return err
+	}
+	parent, err := c.getRecord(ctx, token, u.repo, u.collection, u.rkey)
+	if err != nil {
+		return fmt.Errorf("error fetching parent post %s: %w", pb.replyTo, err)
+	}
+	var parentRecord struct {
+		Reply *ReplyRef `json:"reply"`
+	}
+	if err := json.Unmarshal(parent.Value, &parentRecord); err != nil {
+		return fmt.Errorf("error unmarshaling parent post %s: %w", pb.replyTo, err)
+	}
+
+	reply := &ReplyRef{
+		Parent: StrongRef{URI: parent.Uri, CID: parent.Cid},
+	}
+	if parentRecord.Reply != nil && parentRecord.Reply.Root.URI != "" {
+		reply.Root = parentRecord.Reply.Root
+	} else {
+		// The parent is not a reply itself, so it is the root of the thread
+		reply.Root = reply.Parent
+	}
+	pb.reply = reply
+	pr.Record.Reply = reply
+	return nil
+}
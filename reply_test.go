@@ -0,0 +1,98 @@
+package ltbsky
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReplyToTopLevelPost(t *testing.T) {
+	pds := newFakePDS(t)
+	parentURI := "at://did:plc:other/app.bsky.feed.post/3kparent"
+	parentCid := pds.seed(parentURI, map[string]any{"$type": "app.bsky.feed.post", "text": "Parent"})
+
+	client := pds.newClient(t)
+	uri, err := client.Post(NewPostBuilder("A reply").ReplyTo(parentURI))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	reply := replyOf(t, pds.record(uri))
+	wantRef := map[string]any{"uri": parentURI, "cid": parentCid}
+	if !equalRef(reply["root"], wantRef) {
+		t.Errorf("wanted root %v, got %v", wantRef, reply["root"])
+	}
+	if !equalRef(reply["parent"], wantRef) {
+		t.Errorf("wanted parent %v, got %v", wantRef, reply["parent"])
+	}
+}
+
+func TestReplyToReply(t *testing.T) {
+	pds := newFakePDS(t)
+	rootURI := "at://did:plc:other/app.bsky.feed.post/3kroot"
+	rootCid := pds.seed(rootURI, map[string]any{"$type": "app.bsky.feed.post", "text": "Root"})
+	parentURI := "at://did:plc:third/app.bsky.feed.post/3kparent"
+	parentCid := pds.seed(parentURI, map[string]any{
+		"$type": "app.bsky.feed.post",
+		"text":  "Parent",
+		"reply": map[string]any{
+			"root":   map[string]string{"uri": rootURI, "cid": rootCid},
+			"parent": map[string]string{"uri": rootURI, "cid": rootCid},
+		},
+	})
+
+	client := pds.newClient(t)
+	uri, err := client.Post(NewPostBuilder("A nested reply").ReplyTo(parentURI))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	reply := replyOf(t, pds.record(uri))
+	wantRoot := map[string]any{"uri": rootURI, "cid": rootCid}
+	if !equalRef(reply["root"], wantRoot) {
+		t.Errorf("wanted root %v, got %v", wantRoot, reply["root"])
+	}
+	wantParent := map[string]any{"uri": parentURI, "cid": parentCid}
+	if !equalRef(reply["parent"], wantParent) {
+		t.Errorf("wanted parent %v, got %v", wantParent, reply["parent"])
+	}
+}
+
+func TestReplyToMissingPost(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	_, err := client.Post(NewPostBuilder("A reply").ReplyTo("at://did:plc:other/app.bsky.feed.post/3kmissing"))
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("wanted ErrRecordNotFound, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.createRecord"); n != 0 {
+		t.Errorf("wanted no createRecord calls, got %d", n)
+	}
+}
+
+func TestReplyToInvalidURI(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	_, err := client.Post(NewPostBuilder("A reply").ReplyTo("https://bsky.app/profile/golang.org"))
+	if err == nil {
+		t.Fatal("wanted error, got nil")
+	}
+}
+
+// replyOf returns the reply field of a post record.
+func replyOf(t *testing.T, record map[string]any) map[string]any {
+	t.Helper()
+	if record == nil {
+		t.Fatal("wanted a record, got nil")
+	}
+	reply, ok := record["reply"].(map[string]any)
+	if !ok {
+		t.Fatalf("wanted record to have a reply, got %v", record)
+	}
+	return reply
+}
+
+// equalRef reports whether a decoded strong ref matches want.
+func equalRef(got any, want map[string]any) bool {
+	m, ok := got.(map[string]any)
+	return ok && m["uri"] == want["uri"] && m["cid"] == want["cid"]
+}
@@ -0,0 +1,105 @@
+package ltbsky
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// A RetryPolicy controls how the Client retries requests that fail with a rate
+// limit (status 429), a temporary server error (status 5xx), or a network
+// error.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 1 are treated as 1.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles after every
+	// later attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts. If the server asks the client
+	// to wait longer than MaxDelay, the request is not retried.
+	MaxDelay time.Duration
+	// Jitter is the fraction of each backoff delay, from 0 to 1, that is
+	// randomized to keep clients from retrying in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is used by clients created without WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.2,
+}
+
+// NoRetry is a RetryPolicy that never retries.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// WithRetryPolicy sets the policy used to retry failed requests.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// transportError wraps an error returned by the HTTP client, such as a
+// refused connection, so it can be told apart from errors the server sent.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return "error making request: " + e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// retryable reports whether a request that failed with err may be retried.
+func retryable(err error) bool {
+	var xe *XRPCError
+	if errors.As(err, &xe) {
+		switch xe.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var te *transportError
+	return errors.As(err, &te) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// delay returns how long to wait before attempt number attempt+1, after
+// attempt number attempt failed with err. It returns false if the request
+// should not be retried.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || !retryable(err) {
+		return 0, false
+	}
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff <= 0 || (p.MaxDelay > 0 && backoff > p.MaxDelay) {
+		backoff = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		j := min(p.Jitter, 1)
+		backoff = time.Duration(float64(backoff) * (1 - j + j*rand.Float64()))
+	}
+
+	// Honor the server's requested wait, if it sent one
+	var xe *XRPCError
+	if errors.As(err, &xe) {
+		wait := xe.RetryAfter
+		if xe.StatusCode == http.StatusTooManyRequests && xe.RateLimit != nil && xe.RateLimit.Remaining == 0 && !xe.RateLimit.Reset.IsZero() {
+			wait = max(wait, time.Until(xe.RateLimit.Reset))
+		}
+		if p.MaxDelay > 0 && wait > p.MaxDelay {
+			return 0, false
+		}
+		backoff = max(backoff, wait)
+	}
+	return backoff, true
+}
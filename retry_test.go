@@ -0,0 +1,207 @@
+package ltbsky
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+func TestRetryOnServerError(t *testing.T) {
+	rs := newRetryServer(2, false)
+	defer rs.Close()
+
+	client, err := NewClient(rs.URL, "test.handle", "test.password", WithRetryPolicy(testRetryPolicy))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	uri, err := client.Post(NewPostBuilder("Hello, world!"))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(rs.rkeys) != 3 {
+		t.Fatalf("wanted 3 createRecord calls, got %d", len(rs.rkeys))
+	}
+	for _, rkey := range rs.rkeys[1:] {
+		if rkey != rs.rkeys[0] {
+			t.Errorf("wanted every attempt to use rkey '%s', got '%s'", rs.rkeys[0], rkey)
+		}
+	}
+	if want := "at://did:plc:test/app.bsky.feed.post/" + rs.rkeys[0]; uri != want {
+		t.Errorf("wanted URI '%s', got '%s'", want, uri)
+	}
+}
+
+func TestRetryFindsRecordFromFailedAttempt(t *testing.T) {
+	rs := newRetryServer(1, true)
+	defer rs.Close()
+
+	client, err := NewClient(rs.URL, "test.handle", "test.password", WithRetryPolicy(testRetryPolicy))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	uri, err := client.Post(NewPostBuilder("Hello, world!"))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(rs.rkeys) != 1 {
+		t.Errorf("wanted 1 createRecord call, got %d", len(rs.rkeys))
+	}
+	if want := "at://did:plc:test/app.bsky.feed.post/" + rs.rkeys[0]; uri != want {
+		t.Errorf("wanted URI '%s', got '%s'", want, uri)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	rs := newRetryServer(10, false)
+	defer rs.Close()
+
+	client, err := NewClient(rs.URL, "test.handle", "test.password", WithRetryPolicy(testRetryPolicy))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	_, err = client.Post(NewPostBuilder("Hello, world!"))
+	var xe *XRPCError
+	if !errors.As(err, &xe) || xe.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("wanted status code %d, got %v", http.StatusServiceUnavailable, err)
+	}
+	if len(rs.rkeys) != testRetryPolicy.MaxAttempts {
+		t.Errorf("wanted %d createRecord calls, got %d", testRetryPolicy.MaxAttempts, len(rs.rkeys))
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		want    time.Duration
+		wantOK  bool
+	}{
+		{
+			name:    "Exponential backoff",
+			attempt: 3,
+			err:     &XRPCError{StatusCode: http.StatusBadGateway},
+			want:    400 * time.Millisecond,
+			wantOK:  true,
+		},
+		{
+			name:    "Retry-After",
+			attempt: 1,
+			err:     &XRPCError{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * time.Second},
+			want:    2 * time.Second,
+			wantOK:  true,
+		},
+		{
+			name:    "Retry-After beyond max delay",
+			attempt: 1,
+			err:     &XRPCError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute},
+			wantOK:  false,
+		},
+		{
+			name:    "Rate limit reset beyond max delay",
+			attempt: 1,
+			err: &XRPCError{
+				StatusCode: http.StatusTooManyRequests,
+				RateLimit:  &RateLimit{Remaining: 0, Reset: time.Now().Add(time.Hour)},
+			},
+			wantOK: false,
+		},
+		{
+			name:    "Client error",
+			attempt: 1,
+			err:     &XRPCError{StatusCode: http.StatusBadRequest},
+			wantOK:  false,
+		},
+		{
+			name:    "Max attempts",
+			attempt: 5,
+			err:     &XRPCError{StatusCode: http.StatusServiceUnavailable},
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := p.delay(tt.attempt, tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("wanted ok %t, got %t", tt.wantOK, ok)
+			}
+			if ok && got != tt.want {
+				t.Errorf("wanted delay %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+type retryServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	rkeys   []string
+	created map[string]bool
+}
+
+// newRetryServer returns a mock server whose createRecord fails with status
+// 503 for the first failures calls. If commit is true, the failed calls still
+// create the record, as if only the response was lost.
+func newRetryServer(failures int, commit bool) *retryServer {
+	rs := &retryServer{created: make(map[string]bool)}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte(`{"accessJwt": "test.token", "did": "did:plc:test"}`))
+			if err != nil {
+				return
+			}
+		case "/xrpc/com.atproto.repo.createRecord":
+			var body struct {
+				Rkey string `json:"rkey"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rs.rkeys = append(rs.rkeys, body.Rkey)
+			if len(rs.rkeys) <= failures {
+				if commit {
+					rs.created[body.Rkey] = true
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			rs.created[body.Rkey] = true
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte(`{"uri": "at://did:plc:test/app.bsky.feed.post/` + body.Rkey + `", "cid": "test.cid"}`))
+			if err != nil {
+				return
+			}
+		case "/xrpc/com.atproto.repo.getRecord":
+			rkey := r.URL.Query().Get("rkey")
+			if !rs.created[rkey] {
+				w.WriteHeader(http.StatusBadRequest)
+				_, err := w.Write([]byte(`{"error": "RecordNotFound", "message": "Could not locate record"}`))
+				if err != nil {
+					return
+				}
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte(`{"uri": "at://did:plc:test/app.bsky.feed.post/` + rkey + `", "cid": "test.cid", "value": {}}`))
+			if err != nil {
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return rs
+}
@@ -0,0 +1,167 @@
+package ltbsky
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SearchSort is the order of post search results.
+type SearchSort string
+
+// Orders for SearchPosts.
+const (
+	SortTop    SearchSort = "top"
+	SortLatest SearchSort = "latest"
+)
+
+// SearchPostsOptions narrows the results of SearchPosts. Zero fields do not
+// filter.
+type SearchPostsOptions struct {
+	// Sort orders the results. Zero means SortLatest.
+	Sort SearchSort
+	// Since and Until limit the results to posts created in that range.
+	// Since is inclusive and Until is exclusive.
+	Since time.Time
+	Until time.Time
+	// Lang limits the results to posts in the given language, such as "en".
+	Lang string
+	// Author limits the results to posts by the account with the given
+	// handle or DID.
+	Author string
+	// Mentions limits the results to posts that mention the account with the
+	// given handle or DID.
+	Mentions string
+	// Tags limits the results to posts with all of the given hashtags,
+	// without the leading '#'.
+	Tags []string
+	// Domain limits the results to posts that link to the given domain, such
+	// as "go.dev".
+	Domain string
+	// URL limits the results to posts that link to the given URL.
+	URL string
+	// Limit is the maximum number of posts per page, up to 100. Zero means
+	// the server's default.
+	Limit int
+	// Cursor is the cursor returned with the previous page, or empty for the
+	// first page.
+	Cursor string
+}
+
+// SearchActorsOptions controls the pages returned by SearchActors.
+type SearchActorsOptions struct {
+	// Limit is the maximum number of accounts per page, up to 100. Zero means
+	// the server's default.
+	Limit int
+	// Cursor is the cursor returned with the previous page, or empty for the
+	// first page.
+	Cursor string
+}
+
+// SearchPosts returns a page of posts matching query, and the cursor of the
+// next page. The cursor is empty after the last page. The query uses the
+// same syntax as search in the Bluesky app, such as "from:golang.org".
+func (c *Client) SearchPosts(ctx context.Context, query string, opts SearchPostsOptions) ([]PostView, string, error) {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("error authenticating: %w", err)
+	}
+	params := url.Values{"q": {query}}
+	if opts.Sort != "" {
+		params.Set("sort", string(opts.Sort))
+	}
+	if !opts.Since.IsZero() {
+		params.Set("since", opts.Since.UTC().Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		params.Set("until", opts.Until.UTC().Format(time.RFC3339))
+	}
+	for key, value := range map[string]string{
+		"lang":     opts.Lang,
+		"author":   opts.Author,
+		"mentions": opts.Mentions,
+		"domain":   opts.Domain,
+		"url":      opts.URL,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	for _, tag := range opts.Tags {
+		params.Add("tag", tag)
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		params.Set("cursor", opts.Cursor)
+	}
+	var resp struct {
+		Posts  []PostView `json:"posts"`
+		Cursor string     `json:"cursor"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "app.bsky.feed.searchPosts",
+		params: params,
+		token:  token,
+	}, &resp)
+	if err != nil {
+		return nil, "", fmt.Errorf("error searching posts for %q: %w", query, err)
+	}
+	return resp.Posts, resp.Cursor, nil
+}
+
+// IterSearchPosts returns an iterator over the posts matching query, starting
+// at opts.Cursor and fetching pages as needed. If a page cannot be fetched,
+// the iterator yields the error and stops.
+func (c *Client) IterSearchPosts(ctx context.Context, query string, opts SearchPostsOptions) iter.Seq2[PostView, error] {
+	return paginate(opts.Cursor, func(cursor string) ([]PostView, string, error) {
+		opts.Cursor = cursor
+		return c.SearchPosts(ctx, query, opts)
+	})
+}
+
+// SearchActors returns a page of accounts whose handle, display name, or
+// description matches query, and the cursor of the next page. The cursor is
+// empty after the last page.
+func (c *Client) SearchActors(ctx context.Context, query string, opts SearchActorsOptions) ([]Actor, string, error) {
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("error authenticating: %w", err)
+	}
+	params := url.Values{"q": {query}}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		params.Set("cursor", opts.Cursor)
+	}
+	var resp struct {
+		Actors []Actor `json:"actors"`
+		Cursor string  `json:"cursor"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "app.bsky.actor.searchActors",
+		params: params,
+		token:  token,
+	}, &resp)
+	if err != nil {
+		return nil, "", fmt.Errorf("error searching accounts for %q: %w", query, err)
+	}
+	return resp.Actors, resp.Cursor, nil
+}
+
+// IterSearchActors returns an iterator over the accounts matching query,
+// starting at opts.Cursor and fetching pages as needed. If a page cannot be
+// fetched, the iterator yields the error and stops.
+func (c *Client) IterSearchActors(ctx context.Context, query string, opts SearchActorsOptions) iter.Seq2[Actor, error] {
+	return paginate(opts.Cursor, func(cursor string) ([]Actor, string, error) {
+		opts.Cursor = cursor
+		return c.SearchActors(ctx, query, opts)
+	})
+}
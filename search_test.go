@@ -0,0 +1,121 @@
+package ltbsky
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSearchPosts(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("app.bsky.feed.searchPosts", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		want := "author=alice.bsky.social&cursor=abc&domain=go.dev&lang=en&limit=25&mentions=golang.org&q=gopher&since=2024-01-01T00%3A00%3A00Z&sort=latest&tag=golang&tag=go&until=2024-02-01T00%3A00%3A00Z&url=https%3A%2F%2Fgo.dev%2Fblog"
+		if got := q.Encode(); got != want {
+			t.Errorf("wanted query %s, got %s", want, got)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"posts":  []map[string]any{postView("at://did:plc:alice/app.bsky.feed.post/3kpost", "#golang rocks")},
+			"cursor": "def",
+		})
+	})
+	client := pds.newClient(t)
+
+	posts, cursor, err := client.SearchPosts(context.Background(), "gopher", SearchPostsOptions{
+		Sort:     SortLatest,
+		Since:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Until:    time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		Lang:     "en",
+		Author:   "alice.bsky.social",
+		Mentions: "golang.org",
+		Tags:     []string{"golang", "go"},
+		Domain:   "go.dev",
+		URL:      "https://go.dev/blog",
+		Limit:    25,
+		Cursor:   "abc",
+	})
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(posts) != 1 || cursor != "def" {
+		t.Fatalf("wanted 1 post and cursor def, got %d posts and cursor '%s'", len(posts), cursor)
+	}
+	if posts[0].Record.Text != "#golang rocks" {
+		t.Errorf("wanted text '#golang rocks', got '%s'", posts[0].Record.Text)
+	}
+}
+
+func TestIterSearchPosts(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("app.bsky.feed.searchPosts", func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query(); q.Get("q") != "gopher" || q.Has("sort") || q.Has("since") {
+			t.Errorf("wanted only query gopher, got %v", q)
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		var posts []map[string]any
+		for i := start; i < min(start+2, 5); i++ {
+			posts = append(posts, postView(fmt.Sprintf("at://did:plc:alice/app.bsky.feed.post/%d", i), strconv.Itoa(i)))
+		}
+		resp := map[string]any{"posts": posts}
+		if start+2 < 5 {
+			resp["cursor"] = strconv.Itoa(start + 2)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+	client := pds.newClient(t)
+
+	var texts []string
+	for post, err := range client.IterSearchPosts(context.Background(), "gopher", SearchPostsOptions{}) {
+		if err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+		texts = append(texts, post.Record.Text)
+	}
+	if fmt.Sprint(texts) != "[0 1 2 3 4]" {
+		t.Errorf("wanted posts 0 to 4, got %v", texts)
+	}
+	if n := pds.count("app.bsky.feed.searchPosts"); n != 3 {
+		t.Errorf("wanted 3 requests, got %d", n)
+	}
+}
+
+func TestSearchActors(t *testing.T) {
+	pds := newFakePDS(t)
+	pds.on("app.bsky.actor.searchActors", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("q") != "gopher" || q.Get("limit") != "1" {
+			t.Errorf("wanted query gopher and limit 1, got %v", q)
+		}
+		resp := map[string]any{
+			"actors": []map[string]any{{"did": "did:plc:alice", "handle": "alice.bsky.social", "displayName": "Alice"}},
+		}
+		if q.Get("cursor") == "" {
+			resp["actors"] = []map[string]any{{"did": "did:plc:bob", "handle": "bob.bsky.social"}}
+			resp["cursor"] = "1"
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+	client := pds.newClient(t)
+
+	actors, cursor, err := client.SearchActors(context.Background(), "gopher", SearchActorsOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(actors) != 1 || actors[0].Handle != "bob.bsky.social" || cursor != "1" {
+		t.Fatalf("wanted bob.bsky.social and cursor 1, got %+v and cursor '%s'", actors, cursor)
+	}
+
+	var handles []string
+	for actor, err := range client.IterSearchActors(context.Background(), "gopher", SearchActorsOptions{Limit: 1}) {
+		if err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+		handles = append(handles, actor.Handle)
+	}
+	if fmt.Sprint(handles) != "[bob.bsky.social alice.bsky.social]" {
+		t.Errorf("wanted bob.bsky.social and alice.bsky.social, got %v", handles)
+	}
+}
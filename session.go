@@ -0,0 +1,170 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// refreshMargin is how long before its expiry an access token is refreshed.
+const refreshMargin = 5 * time.Minute
+
+// defaultTokenLifetime is assumed for tokens whose expiry cannot be read.
+const defaultTokenLifetime = 10 * time.Minute
+
+// A Session holds the tokens returned by createSession or refreshSession.
+// Sessions are persisted through a SessionStore.
+type Session struct {
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+	Did        string `json:"did"`
+
+	accessExpiresAt  time.Time
+	refreshExpiresAt time.Time
+}
+
+// newSession creates a Session from a pair of tokens, reading their expiry
+// times from the JWT claims.
+func newSession(accessJwt, refreshJwt, did string) *Session {
+	now := time.Now()
+	s := &Session{
+		AccessJwt:  accessJwt,
+		RefreshJwt: refreshJwt,
+		Did:        did,
+	}
+	exp, err := jwtExpiry(accessJwt)
+	if err != nil {
+		exp = now.Add(defaultTokenLifetime)
+	}
+	s.accessExpiresAt = exp
+	exp, err = jwtExpiry(refreshJwt)
+	if err != nil {
+		exp = now.Add(defaultTokenLifetime)
+	}
+	s.refreshExpiresAt = exp
+	return s
+}
+
+// accessValid reports whether the access token can be used at time now.
+func (s *Session) accessValid(now time.Time) bool {
+	return s.AccessJwt != "" && now.Before(s.accessExpiresAt.Add(-refreshMargin))
+}
+
+// refreshValid reports whether the refresh token can be used at time now.
+func (s *Session) refreshValid(now time.Time) bool {
+	return s.RefreshJwt != "" && now.Before(s.refreshExpiresAt)
+}
+
+// jwtExpiry returns the time stored in a JWT's "exp" claim. The signature is
+// not verified; the token is only inspected to decide when to refresh it.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("malformed token: expected 3 parts, got %d", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error decoding token payload: %w", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("error unmarshaling token claims: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("token has no expiry")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// ensureSession returns a usable access token. It reuses the current session
+// while it is valid, refreshes it when it is close to expiring, and only logs
+// in with the password when there is no session or the refresh fails. If the
+// client has a SessionStore, it is consulted before logging in and updated
+// after every login or refresh.
+func (c *Client) ensureSession(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == nil && c.store != nil {
+		s, err := c.store.Load(c.handle)
+		if err != nil {
+			log.Printf("Error loading stored session for %s: %v", c.handle, err)
+		} else if s != nil {
+			c.session = newSession(s.AccessJwt, s.RefreshJwt, s.Did)
+		}
+	}
+
+	now := time.Now()
+	if c.session != nil && c.session.accessValid(now) {
+		return c.session.AccessJwt, nil
+	}
+	if c.session != nil && c.session.refreshValid(now) {
+		err := c.refresh(ctx)
+		if err == nil {
+			c.saveSession()
+			return c.session.AccessJwt, nil
+		}
+		log.Printf("Error refreshing session, logging in again: %v", err)
+	}
+	if c.session != nil && c.store != nil {
+		if err := c.store.Delete(c.handle); err != nil {
+			log.Printf("Error deleting stored session for %s: %v", c.handle, err)
+		}
+	}
+	if err := c.auth(ctx); err != nil {
+		return "", err
+	}
+	c.saveSession()
+	return c.session.AccessJwt, nil
+}
+
+// sessionDid returns the DID of the logged-in user, or "" before the client
+// has logged in.
+func (c *Client) sessionDid() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session == nil {
+		return ""
+	}
+	return c.session.Did
+}
+
+// saveSession writes the current session to the client's SessionStore, if it
+// has one. Failures are logged rather than returned, since the session is
+// still usable in memory.
+func (c *Client) saveSession() {
+	if c.store == nil || c.session == nil {
+		return
+	}
+	if err := c.store.Save(c.handle, c.session); err != nil {
+		log.Printf("Error saving session for %s: %v", c.handle, err)
+	}
+}
+
+// refresh exchanges the current refresh token for a new session.
+func (c *Client) refresh(ctx context.Context) error {
+	var sessionResponse struct {
+		AccessJwt  string `json:"accessJwt"`
+		RefreshJwt string `json:"refreshJwt"`
+		Did        string `json:"did"`
+	}
+	err := c.xrpc(ctx, &xrpcRequest{
+		method: "POST",
+		nsid:   "com.atproto.server.refreshSession",
+		token:  c.session.RefreshJwt,
+	}, &sessionResponse)
+	if err != nil {
+		return fmt.Errorf("error refreshing session: %w", err)
+	}
+	if sessionResponse.AccessJwt == "" {
+		return fmt.Errorf("refresh response did not include an access token")
+	}
+	c.session = newSession(sessionResponse.AccessJwt, sessionResponse.RefreshJwt, sessionResponse.Did)
+	return nil
+}
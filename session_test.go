@@ -0,0 +1,139 @@
+package ltbsky
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJwtExpiry(t *testing.T) {
+	exp := time.Unix(1_900_000_000, 0)
+	got, err := jwtExpiry(makeJWT(exp))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if !got.Equal(exp) {
+		t.Errorf("wanted expiry %v, got %v", exp, got)
+	}
+	if _, err := jwtExpiry("test.token"); err == nil {
+		t.Error("wanted error for malformed token, got nil")
+	}
+}
+
+func TestSessionReused(t *testing.T) {
+	ss := newSessionServer(time.Hour, http.StatusOK)
+	defer ss.Close()
+
+	client, err := NewClient(ss.URL, "test.handle", "test.password")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	for range 3 {
+		if _, err := client.Post(NewPostBuilder("Hello, world!")); err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+	}
+	if n := ss.creates.Load(); n != 1 {
+		t.Errorf("wanted 1 createSession call, got %d", n)
+	}
+	if n := ss.refreshes.Load(); n != 0 {
+		t.Errorf("wanted 0 refreshSession calls, got %d", n)
+	}
+}
+
+func TestSessionRefreshedNearExpiry(t *testing.T) {
+	ss := newSessionServer(time.Minute, http.StatusOK)
+	defer ss.Close()
+
+	client, err := NewClient(ss.URL, "test.handle", "test.password")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	for range 2 {
+		if _, err := client.Post(NewPostBuilder("Hello, world!")); err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+	}
+	if n := ss.creates.Load(); n != 1 {
+		t.Errorf("wanted 1 createSession call, got %d", n)
+	}
+	if n := ss.refreshes.Load(); n != 1 {
+		t.Errorf("wanted 1 refreshSession call, got %d", n)
+	}
+}
+
+func TestSessionRefreshFailureFallsBackToLogin(t *testing.T) {
+	ss := newSessionServer(time.Minute, http.StatusBadRequest)
+	defer ss.Close()
+
+	client, err := NewClient(ss.URL, "test.handle", "test.password")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	for range 2 {
+		if _, err := client.Post(NewPostBuilder("Hello, world!")); err != nil {
+			t.Fatalf("wanted no error, got %v", err)
+		}
+	}
+	if n := ss.creates.Load(); n != 2 {
+		t.Errorf("wanted 2 createSession calls, got %d", n)
+	}
+	if n := ss.refreshes.Load(); n != 1 {
+		t.Errorf("wanted 1 refreshSession call, got %d", n)
+	}
+}
+
+// makeJWT returns an unsigned JWT whose "exp" claim is set to exp.
+func makeJWT(exp time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	return header + "." + payload + ".sig"
+}
+
+type sessionServer struct {
+	*httptest.Server
+	creates   atomic.Int32
+	refreshes atomic.Int32
+}
+
+// newSessionServer returns a mock server that counts session calls. Access
+// tokens expire after accessTTL, and refreshSession responds with
+// refreshStatus.
+func newSessionServer(accessTTL time.Duration, refreshStatus int) *sessionServer {
+	ss := &sessionServer{}
+	writeSession := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusOK)
+		_, err := fmt.Fprintf(w, `{"accessJwt": %q, "refreshJwt": %q, "did": "did:plc:test"}`,
+			makeJWT(time.Now().Add(accessTTL)), makeJWT(time.Now().Add(24*time.Hour)))
+		if err != nil {
+			return
+		}
+	}
+	ss.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			ss.creates.Add(1)
+			writeSession(w)
+		case "/xrpc/com.atproto.server.refreshSession":
+			ss.refreshes.Add(1)
+			if refreshStatus != http.StatusOK {
+				w.WriteHeader(refreshStatus)
+				return
+			}
+			writeSession(w)
+		case "/xrpc/com.atproto.repo.createRecord":
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte(`{"uri": "test.uri", "cid": "test.cid"}`))
+			if err != nil {
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return ss
+}
@@ -0,0 +1,159 @@
+package ltbsky
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// MaxPostGraphemes is the maximum length of a post's text, in graphemes.
+const MaxPostGraphemes = 300
+
+// SplitOptions controls how SplitIntoThread breaks up text.
+type SplitOptions struct {
+	// MaxGraphemes is the maximum length of each post, including its counter.
+	// Zero means MaxPostGraphemes.
+	MaxGraphemes int
+	// Numbered adds a " 1/n" counter to the end of each post when the text
+	// needs more than one post.
+	Numbered bool
+	// Template, if set, supplies the languages of every post and the images
+	// of the first post.
+	Template *PostBuilder
+}
+
+// SplitIntoThread breaks text into posts that each fit within the grapheme
+// limit, ready to publish with Client.PostThread. It breaks between sentences
+// where it can and between words otherwise, so links, mentions, and hashtags
+// are kept whole. A word longer than the limit, even a link, is broken
+// between graphemes.
+func SplitIntoThread(text string, opts SplitOptions) []*PostBuilder {
+	limit := opts.MaxGraphemes
+	if limit <= 0 {
+		limit = MaxPostGraphemes
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	tokens := splitTokens(text)
+	chunks := chunkTokens(tokens, limit)
+	if opts.Numbered && len(chunks) > 1 {
+		// The counter's width depends on the number of posts, so split again
+		// until the number of posts stops changing
+		for {
+			n := len(chunks)
+			reserve := graphemeCount(fmt.Sprintf(" %d/%d", n, n))
+			chunks = chunkTokens(tokens, max(limit-reserve, 1))
+			if len(chunks) <= n {
+				break
+			}
+		}
+		for i := range chunks {
+			chunks[i] += fmt.Sprintf(" %d/%d", i+1, len(chunks))
+		}
+	}
+
+	builders := make([]*PostBuilder, len(chunks))
+	for i, chunk := range chunks {
+		pb := NewPostBuilder(chunk)
+		if opts.Template != nil {
+			pb.langs = append(pb.langs, opts.Template.langs...)
+			if i == 0 {
+				pb.images = append(pb.images, opts.Template.images...)
+			}
+		}
+		builders[i] = pb
+	}
+	return builders
+}
+
+// splitTokens splits text into words, each with the whitespace before it.
+func splitTokens(text string) []string {
+	var tokens []string
+	start := 0
+	inSpace := true
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if space && !inSpace {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		inSpace = space
+	}
+	return append(tokens, text[start:])
+}
+
+// chunkTokens groups tokens into chunks of at most limit graphemes.
+func chunkTokens(tokens []string, limit int) []string {
+	var chunks []string
+	var cur []string
+	// boundary is the number of tokens in cur that end with a sentence or
+	// paragraph break, or 0 if there is none
+	boundary := 0
+	flush := func(n int) {
+		chunks = append(chunks, strings.TrimSpace(strings.Join(cur[:n], "")))
+		cur = append([]string(nil), cur[n:]...)
+		boundary = 0
+		for i, t := range cur {
+			if endsSentence(t) {
+				boundary = i + 1
+			}
+		}
+		if len(cur) > 0 {
+			cur[0] = strings.TrimLeftFunc(cur[0], unicode.IsSpace)
+		}
+	}
+
+	for _, token := range tokens {
+		if len(cur) > 0 && strings.ContainsAny(leadingSpace(token), "\n\r") {
+			boundary = len(cur)
+		}
+		if len(cur) == 0 {
+			token = strings.TrimLeftFunc(token, unicode.IsSpace)
+		}
+		for len(cur) > 0 && graphemeCount(strings.Join(cur, "")+token) > limit {
+			// Break at the last sentence boundary if it leaves the post at
+			// least half full; otherwise break before this word
+			if boundary > 0 && graphemeCount(strings.Join(cur[:boundary], "")) >= limit/2 {
+				flush(boundary)
+			} else {
+				flush(len(cur))
+				token = strings.TrimLeftFunc(token, unicode.IsSpace)
+			}
+		}
+		if len(cur) == 0 && graphemeCount(token) > limit {
+			// The word alone is too long, so break it between graphemes
+			for graphemeCount(token) > limit {
+				n := 0
+				for range limit {
+					n += graphemeLen(token[n:])
+				}
+				chunks = append(chunks, token[:n])
+				token = token[n:]
+			}
+		}
+		cur = append(cur, token)
+		if endsSentence(token) {
+			boundary = len(cur)
+		}
+	}
+	if len(cur) > 0 {
+		flush(len(cur))
+	}
+	return chunks
+}
+
+// leadingSpace returns the whitespace at the start of token.
+func leadingSpace(token string) string {
+	return token[:len(token)-len(strings.TrimLeftFunc(token, unicode.IsSpace))]
+}
+
+// endsSentence reports whether token ends with sentence punctuation.
+func endsSentence(token string) bool {
+	token = strings.TrimRight(token, `"')]”’`)
+	return strings.HasSuffix(token, ".") || strings.HasSuffix(token, "!") || strings.HasSuffix(token, "?") ||
+		strings.HasSuffix(token, "…") || strings.HasSuffix(token, "。") || strings.HasSuffix(token, "！") ||
+		strings.HasSuffix(token, "？")
+}
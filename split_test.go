@@ -0,0 +1,155 @@
+package ltbsky
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSplitIntoThreadShortText(t *testing.T) {
+	pbs := SplitIntoThread("  Hello, world!  ", SplitOptions{Numbered: true})
+	if len(pbs) != 1 {
+		t.Fatalf("wanted 1 post, got %d", len(pbs))
+	}
+	if pbs[0].content != "Hello, world!" {
+		t.Errorf("wanted content 'Hello, world!', got '%s'", pbs[0].content)
+	}
+	if pbs := SplitIntoThread(" ", SplitOptions{}); len(pbs) != 0 {
+		t.Errorf("wanted no posts, got %d", len(pbs))
+	}
+}
+
+func TestSplitIntoThreadKeepsWords(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog ", 30)
+	pbs := SplitIntoThread(text, SplitOptions{})
+	if len(pbs) < 2 {
+		t.Fatalf("wanted several posts, got %d", len(pbs))
+	}
+	var words []string
+	for i, pb := range pbs {
+		if n := graphemeCount(pb.content); n > MaxPostGraphemes {
+			t.Errorf("post %d: wanted at most %d graphemes, got %d", i, MaxPostGraphemes, n)
+		}
+		words = append(words, strings.Fields(pb.content)...)
+	}
+	if got, want := strings.Join(words, " "), strings.Join(strings.Fields(text), " "); got != want {
+		t.Errorf("wanted the posts to contain every word in order, got '%s'", got)
+	}
+}
+
+func TestSplitIntoThreadPrefersSentences(t *testing.T) {
+	text := "This is the first sentence of the post. This second sentence will not fit in the first post."
+	pbs := SplitIntoThread(text, SplitOptions{MaxGraphemes: 60})
+	if len(pbs) != 2 {
+		t.Fatalf("wanted 2 posts, got %d", len(pbs))
+	}
+	if want := "This is the first sentence of the post."; pbs[0].content != want {
+		t.Errorf("wanted first post '%s', got '%s'", want, pbs[0].content)
+	}
+}
+
+func TestSplitIntoThreadPrefersParagraphs(t *testing.T) {
+	text := "A first paragraph without punctuation\n\nA second paragraph that is long enough to overflow"
+	pbs := SplitIntoThread(text, SplitOptions{MaxGraphemes: 60})
+	if len(pbs) != 2 {
+		t.Fatalf("wanted 2 posts, got %d", len(pbs))
+	}
+	if want := "A first paragraph without punctuation"; pbs[0].content != want {
+		t.Errorf("wanted first post '%s', got '%s'", want, pbs[0].content)
+	}
+}
+
+func TestSplitIntoThreadKeepsTokensWhole(t *testing.T) {
+	url := "https://pkg.go.dev/github.com/fflewddur/ltbsky#SplitIntoThread"
+	text := fmt.Sprintf("Docs are at %s and come from @golang.org about #golang", url)
+	pbs := SplitIntoThread(text, SplitOptions{MaxGraphemes: 70})
+	found := map[string]bool{}
+	for _, pb := range pbs {
+		for _, word := range strings.Fields(pb.content) {
+			found[word] = true
+		}
+	}
+	for _, token := range []string{url, "@golang.org", "#golang"} {
+		if !found[token] {
+			t.Errorf("wanted '%s' to be kept whole, got %v", token, pbs)
+		}
+	}
+}
+
+func TestSplitIntoThreadBreaksLongWords(t *testing.T) {
+	pbs := SplitIntoThread(strings.Repeat("a", 25), SplitOptions{MaxGraphemes: 10})
+	if len(pbs) != 3 {
+		t.Fatalf("wanted 3 posts, got %d", len(pbs))
+	}
+	if pbs[2].content != "aaaaa" {
+		t.Errorf("wanted last post 'aaaaa', got '%s'", pbs[2].content)
+	}
+}
+
+func TestSplitIntoThreadBreaksLongLinks(t *testing.T) {
+	url := "https://example.com/" + strings.Repeat("a", 320)
+	pbs := SplitIntoThread("See "+url, SplitOptions{})
+	if len(pbs) != 3 {
+		t.Fatalf("wanted 3 posts, got %d", len(pbs))
+	}
+	var joined string
+	for i, pb := range pbs {
+		if err := pb.Validate(); err != nil {
+			t.Errorf("post %d: wanted a valid post, got %v", i, err)
+		}
+		joined += pb.content
+	}
+	if joined != "See"+url {
+		t.Errorf("wanted the posts to contain the whole link, got '%s'", joined)
+	}
+}
+
+func TestSplitIntoThreadCountsGraphemes(t *testing.T) {
+	text := strings.Repeat("👍🏽 🇨🇦 ", 20)
+	pbs := SplitIntoThread(text, SplitOptions{MaxGraphemes: 10})
+	// Each post holds 5 emoji and 4 spaces
+	if len(pbs) != 8 {
+		t.Fatalf("wanted 8 posts, got %d", len(pbs))
+	}
+	for i, pb := range pbs {
+		if n := graphemeCount(pb.content); n > 10 {
+			t.Errorf("post %d: wanted at most 10 graphemes, got %d", i, n)
+		}
+	}
+}
+
+func TestSplitIntoThreadNumbered(t *testing.T) {
+	text := strings.Repeat("word ", 100)
+	pbs := SplitIntoThread(text, SplitOptions{MaxGraphemes: 50, Numbered: true})
+	if len(pbs) < 10 {
+		t.Fatalf("wanted at least 10 posts, got %d", len(pbs))
+	}
+	for i, pb := range pbs {
+		suffix := fmt.Sprintf(" %d/%d", i+1, len(pbs))
+		if !strings.HasSuffix(pb.content, suffix) {
+			t.Errorf("post %d: wanted suffix '%s', got '%s'", i, suffix, pb.content)
+		}
+		if n := graphemeCount(pb.content); n > 50 {
+			t.Errorf("post %d: wanted at most 50 graphemes, got %d", i, n)
+		}
+	}
+}
+
+func TestSplitIntoThreadTemplate(t *testing.T) {
+	template := NewPostBuilder("").AddLang("en").AddImageFromPath("./test-data/bsky-go-1.png", "Alt text")
+	pbs := SplitIntoThread(strings.Repeat("word ", 100), SplitOptions{Template: template})
+	if len(pbs) != 2 {
+		t.Fatalf("wanted 2 posts, got %d", len(pbs))
+	}
+	for i, pb := range pbs {
+		if len(pb.langs) != 1 || pb.langs[0] != "en" {
+			t.Errorf("post %d: wanted langs ['en'], got %v", i, pb.langs)
+		}
+	}
+	if len(pbs[0].images) != 1 {
+		t.Errorf("wanted 1 image in the first post, got %d", len(pbs[0].images))
+	}
+	if len(pbs[1].images) != 0 {
+		t.Errorf("wanted no images in the second post, got %d", len(pbs[1].images))
+	}
+}
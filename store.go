@@ -0,0 +1,167 @@
+package ltbsky
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// A SessionStore persists sessions so they can be reused after the process
+// restarts. Sessions are keyed by account handle.
+type SessionStore interface {
+	// Load returns the stored session for handle, or nil if there is none.
+	Load(handle string) (*Session, error)
+	// Save stores the session for handle, replacing any previous session.
+	Save(handle string, s *Session) error
+	// Delete removes the stored session for handle, if there is one.
+	Delete(handle string) error
+}
+
+// FileSessionStore is a SessionStore backed by a JSON file. The file is
+// replaced atomically on every write and is only readable by its owner.
+type FileSessionStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileSessionStore creates a FileSessionStore that reads and writes the
+// file at path. The file is created on the first save.
+func NewFileSessionStore(path string) *FileSessionStore {
+	return &FileSessionStore{path: path}
+}
+
+// Load returns the stored session for handle, or nil if there is none.
+func (f *FileSessionStore) Load(handle string) (*Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sessions, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return sessions[handle], nil
+}
+
+// Save stores the session for handle, replacing any previous session.
+func (f *FileSessionStore) Save(handle string, s *Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sessions, err := f.read()
+	if err != nil {
+		return err
+	}
+	sessions[handle] = s
+	return f.write(sessions)
+}
+
+// Delete removes the stored session for handle, if there is one.
+func (f *FileSessionStore) Delete(handle string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sessions, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := sessions[handle]; !ok {
+		return nil
+	}
+	delete(sessions, handle)
+	return f.write(sessions)
+}
+
+func (f *FileSessionStore) read() (map[string]*Session, error) {
+	sessions := make(map[string]*Session)
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return sessions, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading session file: %w", err)
+	}
+	if err := json.Unmarshal(b, &sessions); err != nil {
+		return nil, fmt.Errorf("error unmarshaling session file: %w", err)
+	}
+	return sessions, nil
+}
+
+func (f *FileSessionStore) write(sessions map[string]*Session) error {
+	b, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling sessions: %w", err)
+	}
+	return writeFileAtomic(f.path, b, 0o600)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as
+// path and then renames it over path, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, os.Remove(tmp.Name()))
+		}
+	}()
+	if err := tmp.Chmod(perm); err != nil {
+		return errors.Join(fmt.Errorf("error setting file permissions: %w", err), tmp.Close())
+	}
+	if _, err := tmp.Write(data); err != nil {
+		return errors.Join(fmt.Errorf("error writing temporary file: %w", err), tmp.Close())
+	}
+	if err := tmp.Sync(); err != nil {
+		return errors.Join(fmt.Errorf("error syncing temporary file: %w", err), tmp.Close())
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temporary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing file: %w", err)
+	}
+	return nil
+}
+
+// MemorySessionStore is a SessionStore that keeps sessions in memory. It is
+// mostly useful for tests.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewMemorySessionStore creates an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*Session)}
+}
+
+// Load returns the stored session for handle, or nil if there is none.
+func (m *MemorySessionStore) Load(handle string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[handle]
+	if !ok {
+		return nil, nil
+	}
+	copied := *s
+	return &copied, nil
+}
+
+// Save stores the session for handle, replacing any previous session.
+func (m *MemorySessionStore) Save(handle string, s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *s
+	m.sessions[handle] = &copied
+	return nil
+}
+
+// Delete removes the stored session for handle, if there is one.
+func (m *MemorySessionStore) Delete(handle string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, handle)
+	return nil
+}
@@ -0,0 +1,143 @@
+package ltbsky
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSessionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	store := NewFileSessionStore(path)
+
+	s, err := store.Load("test.handle")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if s != nil {
+		t.Errorf("wanted no session, got %v", s)
+	}
+
+	want := &Session{AccessJwt: "access", RefreshJwt: "refresh", Did: "did:plc:test"}
+	if err := store.Save("test.handle", want); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("wanted file permissions 0600, got %o", perm)
+	}
+
+	s, err = NewFileSessionStore(path).Load("test.handle")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if s == nil || s.AccessJwt != want.AccessJwt || s.RefreshJwt != want.RefreshJwt || s.Did != want.Did {
+		t.Errorf("wanted session %v, got %v", want, s)
+	}
+
+	if err := store.Delete("test.handle"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	s, err = store.Load("test.handle")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if s != nil {
+		t.Errorf("wanted no session after delete, got %v", s)
+	}
+	matches, err := filepath.Glob(path + ".tmp-*")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("wanted no temporary files, got %v", matches)
+	}
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	store := NewMemorySessionStore()
+	want := &Session{AccessJwt: "access", RefreshJwt: "refresh", Did: "did:plc:test"}
+	if err := store.Save("test.handle", want); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	s, err := store.Load("test.handle")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if s == nil || s.AccessJwt != want.AccessJwt {
+		t.Errorf("wanted session %v, got %v", want, s)
+	}
+	if err := store.Delete("test.handle"); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if s, _ := store.Load("test.handle"); s != nil {
+		t.Errorf("wanted no session after delete, got %v", s)
+	}
+}
+
+func TestClientUsesSessionStore(t *testing.T) {
+	ss := newSessionServer(time.Hour, http.StatusOK)
+	defer ss.Close()
+	store := NewMemorySessionStore()
+
+	// The first client logs in and saves its session
+	client, err := NewClient(ss.URL, "test.handle", "test.password", WithSessionStore(store))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if _, err := client.Post(NewPostBuilder("Hello, world!")); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if s, _ := store.Load("test.handle"); s == nil {
+		t.Fatal("wanted a stored session, got nil")
+	}
+
+	// A second client reuses the stored session instead of logging in
+	client, err = NewClient(ss.URL, "test.handle", "test.password", WithSessionStore(store))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if _, err := client.Post(NewPostBuilder("Hello again!")); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if n := ss.creates.Load(); n != 1 {
+		t.Errorf("wanted 1 createSession call, got %d", n)
+	}
+}
+
+func TestClientSavesRefreshedSession(t *testing.T) {
+	ss := newSessionServer(time.Hour, http.StatusOK)
+	defer ss.Close()
+	store := NewMemorySessionStore()
+	stale := &Session{
+		AccessJwt:  makeJWT(time.Now().Add(-time.Minute)),
+		RefreshJwt: makeJWT(time.Now().Add(time.Hour)),
+		Did:        "did:plc:test",
+	}
+	if err := store.Save("test.handle", stale); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	client, err := NewClient(ss.URL, "test.handle", "test.password", WithSessionStore(store))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if _, err := client.Post(NewPostBuilder("Hello, world!")); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if n := ss.creates.Load(); n != 0 {
+		t.Errorf("wanted 0 createSession calls, got %d", n)
+	}
+	if n := ss.refreshes.Load(); n != 1 {
+		t.Errorf("wanted 1 refreshSession call, got %d", n)
+	}
+	s, _ := store.Load("test.handle")
+	if s == nil || s.AccessJwt == stale.AccessJwt {
+		t.Errorf("wanted stored session to be refreshed, got %v", s)
+	}
+}
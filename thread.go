@@ -0,0 +1,75 @@
+package ltbsky
+
+import (
+	"context"
+	"fmt"
+)
+
+// ThreadError is returned by PostThread when a post in the thread could not
+// be published. The posts before it were published and are not rolled back.
+type ThreadError struct {
+	// Index is the position of the builder that failed.
+	Index int
+	// Published holds refs to the posts that were published before the
+	// failure, in order.
+	Published []StrongRef
+	// Err is the error returned for the failed post.
+	Err error
+}
+
+func (e *ThreadError) Error() string {
+	return fmt.Sprintf("error publishing post %d of thread (%d already published): %v", e.Index, len(e.Published), e.Err)
+}
+
+func (e *ThreadError) Unwrap() error {
+	return e.Err
+}
+
+// PostThread publishes the builders as a thread: the first post is
+// published as given, and each later post is published as a reply to the one
+// before it. It returns refs to all of the published posts.
+//
+// Every post is validated before the first one is published. If a post fails,
+// PostThread stops and returns a *ThreadError reporting which post failed and
+// which were already published. To resume, call PostThread with the remaining
+// builders after calling ReplyTo on the first of them with the URI of the last
+// published post.
+func (c *Client) PostThread(builders ...*PostBuilder) ([]StrongRef, error) {
+	return c.PostThreadContext(context.Background(), builders...)
+}
+
+// PostThreadContext is like PostThread but uses ctx for every request made to
+// the server.
+func (c *Client) PostThreadContext(ctx context.Context, builders ...*PostBuilder) ([]StrongRef, error) {
+	if len(builders) == 0 {
+		return nil, fmt.Errorf("thread has no posts")
+	}
+	// Check every post before publishing any of them
+	for i, pb := range builders {
+		if err := pb.validate(i > 0 || pb.replyTo != "" || pb.reply != nil); err != nil {
+			return nil, &ThreadError{Index: i, Err: err}
+		}
+	}
+	published := make([]StrongRef, 0, len(builders))
+	var root StrongRef
+	for i, pb := range builders {
+		if i > 0 {
+			// Reply to the previous post, replacing any reply set on the builder
+			pb.replyTo = ""
+			pb.reply = &ReplyRef{Root: root, Parent: published[i-1]}
+		}
+		ref, err := c.createPost(ctx, pb)
+		if err != nil {
+			return published, &ThreadError{Index: i, Published: published, Err: err}
+		}
+		if i == 0 {
+			// If the first post is itself a reply, the thread hangs off its root
+			root = *ref
+			if pb.reply != nil {
+				root = pb.reply.Root
+			}
+		}
+		published = append(published, *ref)
+	}
+	return published, nil
+}
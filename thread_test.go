@@ -0,0 +1,102 @@
+package ltbsky
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestPostThread(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+
+	refs, err := client.PostThread(
+		NewPostBuilder("First"),
+		NewPostBuilder("Second"),
+		NewPostBuilder("Third"),
+	)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if len(refs) != 3 {
+		t.Fatalf("wanted 3 refs, got %d", len(refs))
+	}
+	if _, ok := pds.record(refs[0].URI)["reply"]; ok {
+		t.Errorf("wanted first post to have no reply, got %v", pds.record(refs[0].URI))
+	}
+	root := map[string]any{"uri": refs[0].URI, "cid": refs[0].CID}
+	for i := 1; i < len(refs); i++ {
+		reply := replyOf(t, pds.record(refs[i].URI))
+		if !equalRef(reply["root"], root) {
+			t.Errorf("post %d: wanted root %v, got %v", i, root, reply["root"])
+		}
+		parent := map[string]any{"uri": refs[i-1].URI, "cid": refs[i-1].CID}
+		if !equalRef(reply["parent"], parent) {
+			t.Errorf("post %d: wanted parent %v, got %v", i, parent, reply["parent"])
+		}
+	}
+}
+
+func TestPostThreadAsReply(t *testing.T) {
+	pds := newFakePDS(t)
+	rootURI := "at://did:plc:other/app.bsky.feed.post/3kroot"
+	rootCid := pds.seed(rootURI, map[string]any{"$type": "app.bsky.feed.post", "text": "Root"})
+	client := pds.newClient(t)
+
+	refs, err := client.PostThread(
+		NewPostBuilder("First").ReplyTo(rootURI),
+		NewPostBuilder("Second"),
+	)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	root := map[string]any{"uri": rootURI, "cid": rootCid}
+	reply := replyOf(t, pds.record(refs[1].URI))
+	if !equalRef(reply["root"], root) {
+		t.Errorf("wanted root %v, got %v", root, reply["root"])
+	}
+}
+
+func TestPostThreadPartialFailure(t *testing.T) {
+	pds := newFakePDS(t)
+	calls := 0
+	pds.on("com.atproto.repo.createRecord", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 2 {
+			writeError(w, http.StatusBadRequest, "InvalidRequest", "Record is invalid")
+			return
+		}
+		pds.serveDefault(w, r)
+	})
+	client := pds.newClient(t)
+
+	refs, err := client.PostThread(
+		NewPostBuilder("First"),
+		NewPostBuilder("Second"),
+		NewPostBuilder("Third"),
+	)
+	var te *ThreadError
+	if !errors.As(err, &te) {
+		t.Fatalf("wanted *ThreadError, got %v", err)
+	}
+	if te.Index != 1 {
+		t.Errorf("wanted failure at index 1, got %d", te.Index)
+	}
+	if len(te.Published) != 1 || len(refs) != 1 {
+		t.Errorf("wanted 1 published post, got %d and %d", len(te.Published), len(refs))
+	}
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("wanted ErrInvalidRequest, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("wanted 2 createRecord calls, got %d", calls)
+	}
+}
+
+func TestPostThreadEmpty(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	if _, err := client.PostThread(); err == nil {
+		t.Error("wanted error, got nil")
+	}
+}
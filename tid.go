@@ -0,0 +1,36 @@
+package ltbsky
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// tidAlphabet is the base32 alphabet used by atproto timestamp identifiers.
+const tidAlphabet = "234567abcdefghijklmnopqrstuvwxyz"
+
+var (
+	tidMu   sync.Mutex
+	lastTID uint64
+	tidSeq  = rand.Uint64N(1024)
+)
+
+// newTID returns a new timestamp identifier (TID), the record key format used
+// for posts. TIDs sort by creation time and are unique within the process.
+func newTID() string {
+	tidMu.Lock()
+	defer tidMu.Unlock()
+
+	v := uint64(time.Now().UnixMicro())<<10 | tidSeq
+	if v <= lastTID {
+		v = lastTID + 1
+	}
+	lastTID = v
+
+	var b [13]byte
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = tidAlphabet[v&31]
+		v >>= 5
+	}
+	return string(b[:])
+}
@@ -0,0 +1,21 @@
+package ltbsky
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewTID(t *testing.T) {
+	r := regexp.MustCompile(`^[234567abcdefghij][234567abcdefghijklmnopqrstuvwxyz]{12}$`)
+	prev := ""
+	for range 100 {
+		tid := newTID()
+		if !r.MatchString(tid) {
+			t.Fatalf("wanted a valid TID, got '%s'", tid)
+		}
+		if tid <= prev {
+			t.Fatalf("wanted TID '%s' to sort after '%s'", tid, prev)
+		}
+		prev = tid
+	}
+}
@@ -0,0 +1,75 @@
+package ltbsky
+
+import (
+	"context"
+	"fmt"
+)
+
+// RemoveEmbed makes UpdatePost remove the post's images, video, link card, or
+// quoted post, unless the builder adds an embed of its own. It has no effect
+// on new posts.
+func (pb *PostBuilder) RemoveEmbed() *PostBuilder {
+	pb.removeEmbed = true
+	return pb
+}
+
+// UpdatePost replaces the post at atURI with the post built by pb, keeping
+// its URI. The text, facets, languages, and self-labels come from pb, so a
+// builder without languages or self-labels removes the original ones. The
+// original creation time is kept, as are the reply and embed unless pb sets
+// its own; call RemoveEmbed on pb to remove the embed instead. Other fields of
+// the original record are kept as they are. UpdatePost does not change the
+// post's threadgate or postgate, so a builder with RestrictReplies or
+// DisableQuotes is rejected with an error wrapping ErrInvalidPost.
+//
+// If the post changes between being read and being written, UpdatePost
+// fails with an error wrapping ErrInvalidSwap instead of overwriting the
+// other change. It returns a ref to the new version of the post.
+//
+// Bluesky has no edit history, so readers are not told that the post has
+// changed.
+func (c *Client) UpdatePost(ctx context.Context, atURI string, pb *PostBuilder) (StrongRef, error) {
+	u, err := parseATURI(atURI)
+	if err != nil {
+		return StrongRef{}, err
+	}
+	if u.collection != "app.bsky.feed.post" {
+		return StrongRef{}, fmt.Errorf("%s is not a post", atURI)
+	}
+	if err := pb.Validate(); err != nil {
+		return StrongRef{}, err
+	}
+	if pb.replyRules != nil || pb.disableQuotes {
+		return StrongRef{}, fmt.Errorf("%w: reply and quote restrictions cannot be changed by UpdatePost", ErrInvalidPost)
+	}
+	token, err := c.ensureSession(ctx)
+	if err != nil {
+		return StrongRef{}, fmt.Errorf("error authenticating: %w", err)
+	}
+
+	original, err := c.getRecord(ctx, token, u.repo, u.collection, u.rkey)
+	if err != nil {
+		return StrongRef{}, fmt.Errorf("error fetching post %s: %w", atURI, err)
+	}
+	pr, err := c.buildPost(ctx, token, pb)
+	if err != nil {
+		return StrongRef{}, err
+	}
+	fields := []string{"$type", "text", "facets", "langs", "labels"}
+	if pr.Record.Reply != nil {
+		fields = append(fields, "reply")
+	}
+	if pr.Record.Embed != nil || pb.removeEmbed {
+		fields = append(fields, "embed")
+	}
+	updated, err := mergeRecord(original.Value, pr.Record, fields...)
+	if err != nil {
+		return StrongRef{}, fmt.Errorf("error updating post %s: %w", atURI, err)
+	}
+
+	resp, err := c.putRecord(ctx, token, u.repo, u.collection, u.rkey, original.Cid, updated)
+	if err != nil {
+		return StrongRef{}, fmt.Errorf("error updating post %s: %w", atURI, err)
+	}
+	return StrongRef{URI: resp.Uri, CID: resp.Cid}, nil
+}
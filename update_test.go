@@ -0,0 +1,192 @@
+package ltbsky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// seedPost stores a post with a reply, an embed, and other fields that
+// UpdatePost should keep, and returns its URI.
+func seedPost(pds *fakePDS) string {
+	uri := "at://did:plc:test/app.bsky.feed.post/3kpost"
+	pds.seed(uri, map[string]any{
+		"$type":     "app.bsky.feed.post",
+		"text":      "Helo, world! #golang",
+		"createdAt": "2024-01-02T03:04:05Z",
+		"langs":     []string{"en"},
+		"facets": []map[string]any{{
+			"index":    map[string]int{"byteStart": 13, "byteEnd": 20},
+			"features": []map[string]string{{"$type": "app.bsky.richtext.facet#tag", "tag": "golang"}},
+		}},
+		"reply": map[string]any{
+			"root":   map[string]string{"uri": "at://did:plc:other/app.bsky.feed.post/3kroot", "cid": "bafyroot"},
+			"parent": map[string]string{"uri": "at://did:plc:other/app.bsky.feed.post/3kroot", "cid": "bafyroot"},
+		},
+		"embed": map[string]any{
+			"$type":  "app.bsky.embed.record",
+			"record": map[string]string{"uri": "at://did:plc:other/app.bsky.feed.post/3kquoted", "cid": "bafyquoted"},
+		},
+		"tags": []string{"bots"},
+	})
+	return uri
+}
+
+func TestUpdatePost(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := seedPost(pds)
+	client := pds.newClient(t)
+
+	ref, err := client.UpdatePost(context.Background(), uri, NewPostBuilder("Hello, world! https://go.dev"))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if ref.URI != uri {
+		t.Errorf("wanted URI %s, got %s", uri, ref.URI)
+	}
+
+	record := pds.record(uri)
+	if record["text"] != "Hello, world! https://go.dev" {
+		t.Errorf("wanted updated text, got %v", record["text"])
+	}
+	if record["createdAt"] != "2024-01-02T03:04:05Z" {
+		t.Errorf("wanted original createdAt, got %v", record["createdAt"])
+	}
+	if langs, ok := record["langs"]; ok {
+		t.Errorf("wanted langs to be removed, got %v", langs)
+	}
+	if tags, _ := record["tags"].([]any); len(tags) != 1 || tags[0] != "bots" {
+		t.Errorf("wanted original tags, got %v", record["tags"])
+	}
+	reply := replyOf(t, record)
+	if !equalRef(reply["parent"], map[string]any{"uri": "at://did:plc:other/app.bsky.feed.post/3kroot", "cid": "bafyroot"}) {
+		t.Errorf("wanted original reply, got %v", reply)
+	}
+	embed := embedOf(t, record)
+	if !equalRef(embed["record"], map[string]any{"uri": "at://did:plc:other/app.bsky.feed.post/3kquoted", "cid": "bafyquoted"}) {
+		t.Errorf("wanted original embed, got %v", embed)
+	}
+	facets, _ := record["facets"].([]any)
+	if len(facets) != 1 {
+		t.Fatalf("wanted 1 facet, got %v", record["facets"])
+	}
+	features := facets[0].(map[string]any)["features"].([]any)
+	if features[0].(map[string]any)["$type"] != "app.bsky.richtext.facet#link" {
+		t.Errorf("wanted a link facet, got %v", features)
+	}
+}
+
+func TestUpdatePostOverridesEmbedAndLangs(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := seedPost(pds)
+	client := pds.newClient(t)
+
+	pb := NewPostBuilder("Hola, mundo!").
+		AddLang("es").
+		AddCustomLinkCard(LinkCard{URL: "https://go.dev", Title: "Go"})
+	if _, err := client.UpdatePost(context.Background(), uri, pb); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	record := pds.record(uri)
+	if langs, _ := record["langs"].([]any); len(langs) != 1 || langs[0] != "es" {
+		t.Errorf("wanted langs [es], got %v", record["langs"])
+	}
+	if embed := embedOf(t, record); embed["$type"] != "app.bsky.embed.external" {
+		t.Errorf("wanted app.bsky.embed.external, got %v", embed["$type"])
+	}
+	if _, ok := record["facets"]; ok {
+		t.Errorf("wanted no facets, got %v", record["facets"])
+	}
+}
+
+func TestUpdatePostRemoveEmbed(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := seedPost(pds)
+	client := pds.newClient(t)
+
+	if _, err := client.UpdatePost(context.Background(), uri, NewPostBuilder("No quote").RemoveEmbed()); err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	record := pds.record(uri)
+	if embed, ok := record["embed"]; ok {
+		t.Errorf("wanted the embed to be removed, got %v", embed)
+	}
+	if _, ok := record["reply"]; !ok {
+		t.Error("wanted the reply to be kept, got none")
+	}
+}
+
+func TestUpdatePostWithGates(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := seedPost(pds)
+	client := pds.newClient(t)
+
+	for _, pb := range []*PostBuilder{
+		NewPostBuilder("No replies").RestrictReplies(),
+		NewPostBuilder("No quotes").DisableQuotes(),
+	} {
+		_, err := client.UpdatePost(context.Background(), uri, pb)
+		if !errors.Is(err, ErrInvalidPost) {
+			t.Errorf("wanted ErrInvalidPost, got %v", err)
+		}
+	}
+	if n := pds.count("com.atproto.repo.putRecord"); n != 0 {
+		t.Errorf("wanted no putRecord calls, got %d", n)
+	}
+	if uris := pds.uris("app.bsky.feed.threadgate"); len(uris) != 0 {
+		t.Errorf("wanted no threadgate, got %v", uris)
+	}
+}
+
+func TestUpdatePostConcurrentEdit(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := seedPost(pds)
+	// Another client edits the post between our read and write
+	pds.on("com.atproto.repo.putRecord", func(w http.ResponseWriter, r *http.Request) {
+		pds.seed(uri, map[string]any{"$type": "app.bsky.feed.post", "text": "Someone else's edit", "createdAt": "2024-01-02T03:04:05Z"})
+		pds.serveDefault(w, r)
+	})
+	client := pds.newClient(t)
+
+	_, err := client.UpdatePost(context.Background(), uri, NewPostBuilder("Hello, world!"))
+	if !errors.Is(err, ErrInvalidSwap) {
+		t.Fatalf("wanted ErrInvalidSwap, got %v", err)
+	}
+	if text := pds.record(uri)["text"]; text != "Someone else's edit" {
+		t.Errorf("wanted the other edit to be kept, got %v", text)
+	}
+}
+
+func TestUpdatePostRetryAfterLostResponse(t *testing.T) {
+	pds := newFakePDS(t)
+	uri := seedPost(pds)
+	// Write the record, but fail as if the response was lost
+	pds.on("com.atproto.repo.putRecord", func(w http.ResponseWriter, r *http.Request) {
+		pds.serveDefault(httptest.NewRecorder(), r)
+		writeError(w, http.StatusServiceUnavailable, "", "")
+	})
+	client := pds.newClient(t, WithRetryPolicy(testRetryPolicy))
+
+	ref, err := client.UpdatePost(context.Background(), uri, NewPostBuilder("Hello, world!"))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.putRecord"); n != 1 {
+		t.Errorf("wanted 1 putRecord call, got %d", n)
+	}
+	if ref.URI != uri || ref.CID == "" {
+		t.Errorf("wanted a ref to %s, got %v", uri, ref)
+	}
+}
+
+func TestUpdatePostNotAPost(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	_, err := client.UpdatePost(context.Background(), "at://did:plc:test/app.bsky.feed.like/3klike", NewPostBuilder("Hi"))
+	if err == nil {
+		t.Fatal("wanted error, got nil")
+	}
+}
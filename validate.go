@@ -0,0 +1,179 @@
+package ltbsky
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Limits enforced by PostBuilder.Validate.
+const (
+	// MaxPostBytes is the maximum length of a post's text in UTF-8 bytes.
+	MaxPostBytes = 3000
+	// MaxImages is the maximum number of images in a post.
+	MaxImages = 4
+	// MaxAltTextGraphemes is the maximum length of an image's alt text.
+	MaxAltTextGraphemes = 2000
+	// MaxLangs is the maximum number of languages of a post.
+	MaxLangs = 3
+	// MaxVideoAltTextGraphemes is the maximum length of a video's alt text.
+	MaxVideoAltTextGraphemes = 1000
+	// MaxVideoCaptions is the maximum number of caption tracks of a video.
+	MaxVideoCaptions = 20
+	// MaxCaptionBytes is the maximum size of a caption file.
+	MaxCaptionBytes = 20_000
+	// MaxVideoBytes is the maximum size of a video file.
+	MaxVideoBytes = 100_000_000
+	// MaxVideoDuration is the maximum length of a video. It is checked when
+	// the post is sent, before the video is uploaded.
+	MaxVideoDuration = 3 * time.Minute
+)
+
+// ErrInvalidPost is wrapped by every error returned from PostBuilder.Validate.
+var ErrInvalidPost = errors.New("invalid post")
+
+// langTagRegex matches well-formed BCP 47 language tags, such as "en",
+// "pt-BR", or "zh-Hant-TW". It is based on the ABNF in RFC 5646, section 2.1,
+// without the irregular grandfathered tags.
+var langTagRegex = regexp.MustCompile(`(?i)^(?:` +
+	`(?:[a-z]{2,3}(?:-[a-z]{3}){0,3}|[a-z]{4}|[a-z]{5,8})` + // language
+	`(?:-[a-z]{4})?` + // script
+	`(?:-(?:[a-z]{2}|[0-9]{3}))?` + // region
+	`(?:-(?:[a-z0-9]{5,8}|[0-9][a-z0-9]{3}))*` + // variants
+	`(?:-[0-9a-wy-z](?:-[a-z0-9]{2,8})+)*` + // extensions
+	`(?:-x(?:-[a-z0-9]{1,8})+)?` + // private use
+	`|x(?:-[a-z0-9]{1,8})+)$`)
+
+// Validate checks the post against Bluesky's limits without contacting the
+// server. It returns nil if the post is valid, or an error joining every
+// problem found. Each of those errors wraps ErrInvalidPost.
+func (pb *PostBuilder) Validate() error {
+	return pb.validate(pb.replyTo != "" || pb.reply != nil)
+}
+
+// validate checks the post like Validate, treating it as a reply if isReply
+// is set, such as for a later post of a thread.
+func (pb *PostBuilder) validate(isReply bool) error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidPost}, args...)...))
+	}
+
+	if n := graphemeCount(pb.content); n > MaxPostGraphemes {
+		invalid("text is %d graphemes, over the limit of %d", n, MaxPostGraphemes)
+	}
+	if n := len(pb.content); n > MaxPostBytes {
+		invalid("text is %d bytes, over the limit of %d", n, MaxPostBytes)
+	}
+	if n := len(pb.images); n > MaxImages {
+		invalid("post has %d images, over the limit of %d", n, MaxImages)
+	}
+	if pb.linkCard != nil && len(pb.images) > 0 {
+		invalid("post cannot have both images and a link card")
+	}
+	if pb.video != nil && len(pb.images) > 0 {
+		invalid("post cannot have both images and a video")
+	}
+	if pb.video != nil && pb.linkCard != nil {
+		invalid("post cannot have both a video and a link card")
+	}
+	if pb.video != nil {
+		if n := graphemeCount(pb.video.Alt); n > MaxVideoAltTextGraphemes {
+			invalid("alt text of video is %d graphemes, over the limit of %d", n, MaxVideoAltTextGraphemes)
+		}
+		n := int64(len(pb.video.Bytes))
+		if n == 0 && pb.video.Path != "" {
+			// A missing file is reported when the post is built
+			if info, err := os.Stat(pb.video.Path); err == nil {
+				n = info.Size()
+			}
+		}
+		if n > MaxVideoBytes {
+			invalid("video is %d bytes, over the limit of %d", n, MaxVideoBytes)
+		}
+	}
+	if len(pb.captions) > 0 && pb.video == nil {
+		invalid("post has captions but no video")
+	}
+	if n := len(pb.captions); n > MaxVideoCaptions {
+		invalid("video has %d captions, over the limit of %d", n, MaxVideoCaptions)
+	}
+	for _, c := range pb.captions {
+		if !langTagRegex.MatchString(c.Lang) {
+			invalid("captions language %q is not a valid BCP 47 language tag", c.Lang)
+		}
+		n := int64(len(c.Bytes))
+		if n == 0 && c.Path != "" {
+			// A missing file is reported when the post is built
+			if info, err := os.Stat(c.Path); err == nil {
+				n = info.Size()
+			}
+		}
+		if n > MaxCaptionBytes {
+			invalid("%s captions are %d bytes, over the limit of %d", c.Lang, n, MaxCaptionBytes)
+		}
+	}
+	if pb.linkCard != nil {
+		if u, err := url.Parse(pb.linkCard.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("link card URL %q is not an http or https URL", pb.linkCard.URL)
+		}
+	}
+	for i, img := range pb.images {
+		if n := graphemeCount(img.Alt); n > MaxAltTextGraphemes {
+			invalid("alt text of image %d is %d graphemes, over the limit of %d", i, n, MaxAltTextGraphemes)
+		}
+	}
+	if n := len(pb.langs); n > MaxLangs {
+		invalid("post has %d languages, over the limit of %d", n, MaxLangs)
+	}
+	for _, lang := range pb.langs {
+		if !langTagRegex.MatchString(lang) {
+			invalid("%q is not a valid BCP 47 language tag", lang)
+		}
+	}
+	if pb.replyTo != "" {
+		if _, err := parseATURI(pb.replyTo); err != nil {
+			invalid("reply parent: %v", err)
+		}
+	}
+	if pb.quoteOf != "" {
+		if _, err := parseATURI(pb.quoteOf); err != nil {
+			invalid("quoted post: %v", err)
+		}
+	}
+	if pb.replyRules != nil && isReply {
+		invalid("only the first post of a thread can restrict replies")
+	}
+	if n := len(pb.replyRules); n > MaxReplyRules {
+		invalid("post has %d reply rules, over the limit of %d", n, MaxReplyRules)
+	}
+	for _, rule := range pb.replyRules {
+		switch rule.Type {
+		case MentionedCanReply.Type, FollowingCanReply.Type, FollowersCanReply.Type:
+		case "app.bsky.feed.threadgate#listRule":
+			if u, err := parseATURI(rule.List); err != nil || u.collection != "app.bsky.graph.list" {
+				invalid("reply rule list %q is not a list URI", rule.List)
+			}
+		default:
+			invalid("unknown reply rule %q", rule.Type)
+		}
+	}
+	if n := len(pb.selfLabels); n > MaxSelfLabels {
+		invalid("post has %d self-labels, over the limit of %d", n, MaxSelfLabels)
+	}
+	for _, l := range pb.selfLabels {
+		switch {
+		case l.val == "":
+			invalid("self-label is empty")
+		case len(l.val) > MaxLabelBytes:
+			invalid("self-label %q is %d bytes, over the limit of %d", l.val, len(l.val), MaxLabelBytes)
+		case !l.custom && !slices.Contains(knownSelfLabels, l.val):
+			invalid("unknown self-label %q", l.val)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,217 @@
+package ltbsky
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		pb       *PostBuilder
+		wantErrs int
+	}{
+		{
+			name: "Valid",
+			pb:   NewPostBuilder("Hello, world! https://go.dev @golang.org #golang").AddLang("en").AddLang("zh-Hant-TW"),
+		},
+		{
+			name: "Exactly at the grapheme limit",
+			pb:   NewPostBuilder(strings.Repeat("👍🏽", MaxPostGraphemes)),
+		},
+		{
+			name:     "Too many graphemes",
+			pb:       NewPostBuilder(strings.Repeat("a", MaxPostGraphemes+1)),
+			wantErrs: 1,
+		},
+		{
+			name:     "Too many bytes",
+			pb:       NewPostBuilder(strings.Repeat("👨‍👩‍👧‍👦", 150)),
+			wantErrs: 1,
+		},
+		{
+			name: "Too many images",
+			pb: NewPostBuilder("Images").
+				AddImageFromBytes([]byte("1"), "1").
+				AddImageFromBytes([]byte("2"), "2").
+				AddImageFromBytes([]byte("3"), "3").
+				AddImageFromBytes([]byte("4"), "4").
+				AddImageFromBytes([]byte("5"), "5"),
+			wantErrs: 1,
+		},
+		{
+			name:     "Alt text too long",
+			pb:       NewPostBuilder("Image").AddImageFromBytes([]byte("1"), strings.Repeat("a", MaxAltTextGraphemes+1)),
+			wantErrs: 1,
+		},
+		{
+			name:     "Invalid language tags",
+			pb:       NewPostBuilder("Langs").AddLang("e").AddLang("en_US"),
+			wantErrs: 2,
+		},
+		{
+			name:     "Too many languages",
+			pb:       NewPostBuilder("Langs").AddLang("en").AddLang("es").AddLang("fr").AddLang("de"),
+			wantErrs: 1,
+		},
+		{
+			name:     "Images and a link card",
+			pb:       NewPostBuilder("Card").AddImageFromBytes([]byte("1"), "1").AddLinkCard("https://go.dev"),
+			wantErrs: 1,
+		},
+		{
+			name:     "Invalid link card URL",
+			pb:       NewPostBuilder("Card").AddLinkCard("go.dev"),
+			wantErrs: 1,
+		},
+		{
+			name: "Video with captions",
+			pb: NewPostBuilder("Video").
+				AddVideoFromPath("video.mp4", "Alt").
+				AddVideoCaptionFromBytes("en", []byte("WEBVTT")).
+				AddVideoCaptionFromPath("pt-BR", "captions.vtt"),
+		},
+		{
+			name: "Video and other media",
+			pb: NewPostBuilder("Video").
+				AddVideoFromPath("video.mp4", "Alt").
+				AddImageFromBytes([]byte("1"), "1").
+				AddLinkCard("https://go.dev"),
+			wantErrs: 3,
+		},
+		{
+			name: "Invalid captions",
+			pb: NewPostBuilder("Video").
+				AddVideoFromPath("video.mp4", strings.Repeat("a", MaxVideoAltTextGraphemes+1)).
+				AddVideoCaptionFromBytes("en_US", []byte("WEBVTT")).
+				AddVideoCaptionFromBytes("en", make([]byte, MaxCaptionBytes+1)),
+			wantErrs: 3,
+		},
+		{
+			name:     "Captions without a video",
+			pb:       NewPostBuilder("Captions").AddVideoCaptionFromBytes("en", []byte("WEBVTT")),
+			wantErrs: 1,
+		},
+		{
+			name:     "Invalid record URIs",
+			pb:       NewPostBuilder("Links").ReplyTo("https://bsky.app").Quote("at://did:plc:abc"),
+			wantErrs: 2,
+		},
+		{
+			name: "Mention inside a link",
+			pb:   NewPostBuilder("See https://example.com/@golang.org"),
+		},
+		{
+			name: "Reply rules",
+			pb:   NewPostBuilder("Gated").RestrictReplies(MentionedCanReply, ListMembersCanReply("at://did:plc:test/app.bsky.graph.list/3klist")).DisableQuotes(),
+		},
+		{
+			name:     "Reply rules on a reply",
+			pb:       NewPostBuilder("Gated").ReplyTo("at://did:plc:test/app.bsky.feed.post/3kpost").RestrictReplies(),
+			wantErrs: 1,
+		},
+		{
+			name:     "Reply rule with a post URI",
+			pb:       NewPostBuilder("Gated").RestrictReplies(ListMembersCanReply("at://did:plc:test/app.bsky.feed.post/3kpost")),
+			wantErrs: 1,
+		},
+		{
+			name: "Too many reply rules",
+			pb: NewPostBuilder("Gated").RestrictReplies(MentionedCanReply, FollowingCanReply, FollowersCanReply,
+				ListMembersCanReply("at://did:plc:test/app.bsky.graph.list/1"),
+				ListMembersCanReply("at://did:plc:test/app.bsky.graph.list/2"),
+				ListMembersCanReply("at://did:plc:test/app.bsky.graph.list/3")),
+			wantErrs: 1,
+		},
+		{
+			name: "Self-labels",
+			pb:   NewPostBuilder("Labeled").AddSelfLabel(LabelGraphicMedia).AddSelfLabel(LabelNudity).AddCustomSelfLabel("spoiler"),
+		},
+		{
+			name:     "Unknown self-label",
+			pb:       NewPostBuilder("Labeled").AddSelfLabel("spoiler"),
+			wantErrs: 1,
+		},
+		{
+			name:     "Empty self-label",
+			pb:       NewPostBuilder("Labeled").AddCustomSelfLabel(""),
+			wantErrs: 1,
+		},
+		{
+			name:     "Long self-label",
+			pb:       NewPostBuilder("Labeled").AddCustomSelfLabel(strings.Repeat("a", MaxLabelBytes+1)),
+			wantErrs: 1,
+		},
+		{
+			name:     "Several problems",
+			pb:       NewPostBuilder(strings.Repeat("a", MaxPostBytes+1)).AddLang("not a tag"),
+			wantErrs: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.pb.Validate()
+			if tt.wantErrs == 0 {
+				if err != nil {
+					t.Fatalf("wanted no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidPost) {
+				t.Fatalf("wanted ErrInvalidPost, got %v", err)
+			}
+			joined, ok := err.(interface{ Unwrap() []error })
+			if !ok {
+				t.Fatalf("wanted a joined error, got %T", err)
+			}
+			if n := len(joined.Unwrap()); n != tt.wantErrs {
+				t.Errorf("wanted %d errors, got %d: %v", tt.wantErrs, n, err)
+			}
+		})
+	}
+}
+
+func TestValidateCaptionFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "captions.vtt")
+	if err := os.WriteFile(path, make([]byte, MaxCaptionBytes+1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pb := NewPostBuilder("Video").
+		AddVideoFromPath("video.mp4", "Alt").
+		AddVideoCaptionFromPath("en", path)
+	if err := pb.Validate(); !errors.Is(err, ErrInvalidPost) {
+		t.Errorf("wanted ErrInvalidPost, got %v", err)
+	}
+}
+
+func TestPostValidatesBeforeLogin(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	_, err := client.Post(NewPostBuilder(strings.Repeat("a", MaxPostGraphemes+1)))
+	if !errors.Is(err, ErrInvalidPost) {
+		t.Fatalf("wanted ErrInvalidPost, got %v", err)
+	}
+	if n := pds.count("com.atproto.server.createSession"); n != 0 {
+		t.Errorf("wanted no createSession calls, got %d", n)
+	}
+}
+
+func TestPostThreadValidatesEveryPost(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t)
+	_, err := client.PostThread(
+		NewPostBuilder("Valid"),
+		NewPostBuilder("Invalid").AddLang("not a tag"),
+	)
+	var te *ThreadError
+	if !errors.As(err, &te) || te.Index != 1 {
+		t.Fatalf("wanted *ThreadError at index 1, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.createRecord"); n != 0 {
+		t.Errorf("wanted no createRecord calls, got %d", n)
+	}
+}
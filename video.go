@@ -0,0 +1,329 @@
+package ltbsky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultVideoService is the service that processes uploaded videos unless
+// WithVideoService is used.
+const DefaultVideoService = "https://video.bsky.app"
+
+// defaultVideoPollInterval is how often the video service is asked whether
+// an uploaded video has been processed.
+const defaultVideoPollInterval = time.Second
+
+// defaultVideoProcessingTimeout is how long to wait for the video service to
+// process an uploaded video before giving up.
+const defaultVideoProcessingTimeout = 10 * time.Minute
+
+// Video processing job states reported by the video service.
+const (
+	videoJobCompleted = "JOB_STATE_COMPLETED"
+	videoJobFailed    = "JOB_STATE_FAILED"
+)
+
+// WithVideoService makes the Client upload videos to the video service at
+// url instead of DefaultVideoService.
+func WithVideoService(url string) ClientOption {
+	return func(c *Client) {
+		c.videoService = url
+	}
+}
+
+type localVideo struct {
+	Path   string
+	Reader io.Reader
+	Bytes  []byte
+	Alt    string
+}
+
+func (l *localVideo) String() string {
+	return fmt.Sprintf("localVideo{Path: %q, Bytes: []byte len=%d, Alt: %q}", l.Path, len(l.Bytes), l.Alt)
+}
+
+// load reads the video from disk or its reader, if it has not been read yet.
+func (l *localVideo) load() error {
+	if len(l.Bytes) > 0 {
+		return nil
+	}
+	var err error
+	switch {
+	case l.Reader != nil:
+		l.Bytes, err = io.ReadAll(l.Reader)
+		l.Reader = nil
+	case l.Path != "":
+		l.Bytes, err = os.ReadFile(l.Path)
+	}
+	return err
+}
+
+type localCaption struct {
+	Lang  string
+	Path  string
+	Bytes []byte
+}
+
+// AddVideoFromPath adds an MP4 video to the post from disk, replacing any
+// video already added. A post can have one video.
+func (pb *PostBuilder) AddVideoFromPath(path string, alt string) *PostBuilder {
+	pb.video = &localVideo{
+		Path: path,
+		Alt:  alt,
+	}
+	return pb
+}
+
+// AddVideoFromReader adds an MP4 video to the post, read from r when the post
+// is sent. It replaces any video already added.
+func (pb *PostBuilder) AddVideoFromReader(r io.Reader, alt string) *PostBuilder {
+	pb.video = &localVideo{
+		Reader: r,
+		Alt:    alt,
+	}
+	return pb
+}
+
+// AddVideoCaptionFromPath adds WebVTT captions in the language lang to the
+// post's video from disk.
+func (pb *PostBuilder) AddVideoCaptionFromPath(lang, path string) *PostBuilder {
+	pb.captions = append(pb.captions, &localCaption{
+		Lang: lang,
+		Path: path,
+	})
+	return pb
+}
+
+// AddVideoCaptionFromBytes adds WebVTT captions in the language lang to the
+// post's video from memory.
+func (pb *PostBuilder) AddVideoCaptionFromBytes(lang string, data []byte) *PostBuilder {
+	pb.captions = append(pb.captions, &localCaption{
+		Lang:  lang,
+		Bytes: data,
+	})
+	return pb
+}
+
+// A VideoCaption is a caption track of a video.
+type VideoCaption struct {
+	Lang string `json:"lang"`
+	File *Blob  `json:"file"`
+}
+
+// videoJobStatus is the state of a video processing job.
+type videoJobStatus struct {
+	JobId   string `json:"jobId"`
+	State   string `json:"state"`
+	Blob    *Blob  `json:"blob,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// embedVideoInPost uploads the post's video and captions, and embeds them in
+// the post record.
+func (c *Client) embedVideoInPost(ctx context.Context, pb *PostBuilder, pr *postRequest, token string) error {
+	if pb.video == nil {
+		return nil
+	}
+	if err := pb.video.load(); err != nil {
+		return fmt.Errorf("error reading %s: %w", pb.video, err)
+	}
+	// Check the limits before spending time on the upload
+	if n := len(pb.video.Bytes); n > MaxVideoBytes {
+		return fmt.Errorf("%w: video is %d bytes, over the limit of %d", ErrInvalidPost, n, MaxVideoBytes)
+	}
+	duration, err := mp4Duration(pb.video.Bytes)
+	if err != nil {
+		log.Printf("Error reading duration of %s: %v", pb.video, err)
+	} else if duration > MaxVideoDuration {
+		return fmt.Errorf("%w: video is %v long, over the limit of %v", ErrInvalidPost, duration, MaxVideoDuration)
+	}
+	videoEmbed := &Embed{
+		Type: "app.bsky.embed.video",
+		Alt:  pb.video.Alt,
+	}
+	width, height, err := mp4Dimensions(pb.video.Bytes)
+	if err != nil {
+		log.Printf("Error reading dimensions of %s: %v", pb.video, err)
+	} else {
+		videoEmbed.AspectRatio = &AspectRatio{Width: width, Height: height}
+	}
+
+	videoEmbed.Video, err = c.uploadVideo(ctx, token, pb.video.Bytes)
+	if err != nil {
+		return fmt.Errorf("error uploading %s: %w", pb.video, err)
+	}
+
+	for _, vc := range pb.captions {
+		data := vc.Bytes
+		if len(data) == 0 && vc.Path != "" {
+			data, err = os.ReadFile(vc.Path)
+			if err != nil {
+				return fmt.Errorf("error reading %s captions: %w", vc.Lang, err)
+			}
+		}
+		blob, err := c.uploadBlob(ctx, token, data, "text/vtt")
+		if err != nil {
+			return fmt.Errorf("error uploading %s captions: %w", vc.Lang, err)
+		}
+		videoEmbed.Captions = append(videoEmbed.Captions, &VideoCaption{Lang: vc.Lang, File: blob})
+	}
+
+	pr.Record.Embed = videoEmbed
+	return nil
+}
+
+// uploadVideo uploads a video to the video service and waits for it to be
+// processed, for at most the client's video processing timeout. The video
+// service stores the processed video in the user's repo and returns a
+// reference to the blob.
+func (c *Client) uploadVideo(ctx context.Context, token string, data []byte) (*Blob, error) {
+	did, aud, err := c.pdsServiceDID(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("error finding PDS: %w", err)
+	}
+
+	// The video service writes the blob to the PDS on our behalf, using a
+	// short-lived token that only allows uploading blobs
+	var serviceAuth struct {
+		Token string `json:"token"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "com.atproto.server.getServiceAuth",
+		params: url.Values{
+			"aud": {aud},
+			"lxm": {"com.atproto.repo.uploadBlob"},
+			"exp": {fmt.Sprint(time.Now().Add(30 * time.Minute).Unix())},
+		},
+		token: token,
+	}, &serviceAuth)
+	if err != nil {
+		return nil, fmt.Errorf("error getting service auth: %w", err)
+	}
+
+	// Depending on its version, the service returns the job status at the top
+	// level or under jobStatus
+	var uploadResponse struct {
+		videoJobStatus
+		JobStatus *videoJobStatus `json:"jobStatus"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "POST",
+		host:   c.videoService,
+		nsid:   "app.bsky.video.uploadVideo",
+		params: url.Values{
+			"did":  {did},
+			"name": {newTID() + ".mp4"},
+		},
+		rawBody:     data,
+		contentType: "video/mp4",
+		token:       serviceAuth.Token,
+	}, &uploadResponse)
+	var status videoJobStatus
+	if err != nil {
+		// A video uploaded before is not processed again; poll the job that
+		// processed it instead
+		jobID, ok := existingVideoJob(err)
+		if !ok {
+			return nil, err
+		}
+		status.JobId = jobID
+	} else {
+		status = uploadResponse.videoJobStatus
+		if uploadResponse.JobStatus != nil {
+			status = *uploadResponse.JobStatus
+		}
+	}
+
+	deadline := time.Now().Add(c.videoProcessingTimeout)
+	for {
+		switch {
+		case status.Blob != nil:
+			return status.Blob, nil
+		case status.State == videoJobFailed:
+			return nil, fmt.Errorf("video processing failed: %s", strings.TrimSpace(status.Error+" "+status.Message))
+		case status.State == videoJobCompleted:
+			return nil, errors.New("video processing completed without a blob")
+		case time.Now().After(deadline):
+			return nil, fmt.Errorf("video processing did not finish within %v (job %s is %s)", c.videoProcessingTimeout, status.JobId, status.State)
+		}
+
+		timer := time.NewTimer(c.videoPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		var statusResponse struct {
+			JobStatus videoJobStatus `json:"jobStatus"`
+		}
+		err = c.xrpc(ctx, &xrpcRequest{
+			method: "GET",
+			host:   c.videoService,
+			nsid:   "app.bsky.video.getJobStatus",
+			params: url.Values{"jobId": {status.JobId}},
+		}, &statusResponse)
+		if err != nil {
+			return nil, fmt.Errorf("error checking video processing status: %w", err)
+		}
+		status = statusResponse.JobStatus
+	}
+}
+
+// existingVideoJob returns the ID of the job named in an already_exists error
+// from the video service, which is returned for a video it has seen before.
+func existingVideoJob(err error) (string, bool) {
+	var xerr *XRPCError
+	if !errors.As(err, &xerr) || xerr.Name != "already_exists" {
+		return "", false
+	}
+	var status videoJobStatus
+	if json.Unmarshal(xerr.body, &status) != nil || status.JobId == "" {
+		return "", false
+	}
+	return status.JobId, true
+}
+
+// pdsServiceDID returns the user's DID and the did:web DID of the PDS that
+// hosts their repo, which may differ from the server the client logs in to.
+func (c *Client) pdsServiceDID(ctx context.Context, token string) (did, pdsDID string, err error) {
+	var session struct {
+		Did    string `json:"did"`
+		DidDoc struct {
+			Service []struct {
+				ID              string `json:"id"`
+				ServiceEndpoint string `json:"serviceEndpoint"`
+			} `json:"service"`
+		} `json:"didDoc"`
+	}
+	err = c.xrpc(ctx, &xrpcRequest{
+		method: "GET",
+		nsid:   "com.atproto.server.getSession",
+		token:  token,
+	}, &session)
+	if err != nil {
+		return "", "", err
+	}
+	endpoint := c.server
+	for _, s := range session.DidDoc.Service {
+		if s.ID == "#atproto_pds" || strings.HasSuffix(s.ID, "#atproto_pds") {
+			endpoint = s.ServiceEndpoint
+		}
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	return session.Did, "did:web:" + strings.ReplaceAll(u.Host, ":", "%3A"), nil
+}
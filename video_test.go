@@ -0,0 +1,213 @@
+package ltbsky
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// handleVideoService makes the fake PDS act as the video service. The job
+// reports the given states when polled, then completes.
+func handleVideoService(t *testing.T, pds *fakePDS, states ...string) {
+	wantAuth := "Bearer service.token:did:web:" + url.QueryEscape(strings.TrimPrefix(pds.URL, "http://")) + ":com.atproto.repo.uploadBlob"
+	pds.on("app.bsky.video.uploadVideo", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != wantAuth {
+			t.Errorf("wanted Authorization '%s', got '%s'", wantAuth, got)
+		}
+		if got := r.URL.Query().Get("did"); got != pds.did {
+			t.Errorf("wanted did %s, got %s", pds.did, got)
+		}
+		if got := r.Header.Get("Content-Type"); got != "video/mp4" {
+			t.Errorf("wanted Content-Type video/mp4, got %s", got)
+		}
+		if _, err := io.Copy(io.Discard, r.Body); err != nil {
+			t.Errorf("wanted no error reading video, got %v", err)
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"jobId": "job1", "did": pds.did, "state": "JOB_STATE_CREATED"})
+	})
+	polls := 0
+	pds.on("app.bsky.video.getJobStatus", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("jobId"); got != "job1" {
+			t.Errorf("wanted jobId job1, got %s", got)
+		}
+		status := map[string]any{"jobId": "job1", "did": pds.did}
+		if polls < len(states) {
+			status["state"] = states[polls]
+			if states[polls] == videoJobFailed {
+				status["error"] = "Video too long"
+			}
+		} else {
+			status["state"] = videoJobCompleted
+			status["blob"] = map[string]any{
+				"$type":    "blob",
+				"ref":      map[string]string{"$link": "bafyvideo"},
+				"mimeType": "video/mp4",
+				"size":     1234,
+			}
+		}
+		polls++
+		writeJSON(w, http.StatusOK, map[string]any{"jobStatus": status})
+	})
+}
+
+func TestPostVideo(t *testing.T) {
+	pds := newFakePDS(t)
+	handleVideoService(t, pds, "JOB_STATE_ENCODING", "JOB_STATE_SCANNING")
+	client := pds.newClient(t, WithVideoService(pds.URL))
+	client.videoPollInterval = time.Millisecond
+
+	pb := NewPostBuilder("A screen recording").
+		AddVideoFromReader(bytes.NewReader(makeMP4(1280, 720)), "Installing Go").
+		AddVideoCaptionFromBytes("en", []byte("WEBVTT\n\n00:00.000 --> 00:01.000\nHello\n"))
+	uri, err := client.Post(pb)
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+
+	if n := pds.count("app.bsky.video.getJobStatus"); n != 3 {
+		t.Errorf("wanted 3 job status checks, got %d", n)
+	}
+	embed := embedOf(t, pds.record(uri))
+	if embed["$type"] != "app.bsky.embed.video" {
+		t.Errorf("wanted app.bsky.embed.video, got %v", embed["$type"])
+	}
+	if embed["alt"] != "Installing Go" {
+		t.Errorf("wanted alt 'Installing Go', got %v", embed["alt"])
+	}
+	video, _ := embed["video"].(map[string]any)
+	if ref, _ := video["ref"].(map[string]any); ref["$link"] != "bafyvideo" {
+		t.Errorf("wanted video blob bafyvideo, got %v", embed["video"])
+	}
+	ratio, _ := embed["aspectRatio"].(map[string]any)
+	if ratio["width"] != 1280.0 || ratio["height"] != 720.0 {
+		t.Errorf("wanted aspect ratio 1280x720, got %v", embed["aspectRatio"])
+	}
+	captions, _ := embed["captions"].([]any)
+	if len(captions) != 1 {
+		t.Fatalf("wanted 1 caption, got %v", embed["captions"])
+	}
+	caption, _ := captions[0].(map[string]any)
+	if caption["lang"] != "en" {
+		t.Errorf("wanted caption lang en, got %v", caption["lang"])
+	}
+	if file, _ := caption["file"].(map[string]any); file["mimeType"] != "text/vtt" {
+		t.Errorf("wanted caption file of type text/vtt, got %v", caption["file"])
+	}
+}
+
+func TestPostVideoProcessingFails(t *testing.T) {
+	pds := newFakePDS(t)
+	handleVideoService(t, pds, "JOB_STATE_ENCODING", videoJobFailed)
+	client := pds.newClient(t, WithVideoService(pds.URL))
+	client.videoPollInterval = time.Millisecond
+
+	pb := NewPostBuilder("A screen recording").AddVideoFromReader(bytes.NewReader(makeMP4(1280, 720)), "")
+	_, err := client.Post(pb)
+	if err == nil || !strings.Contains(err.Error(), "Video too long") {
+		t.Fatalf("wanted processing error, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.createRecord"); n != 0 {
+		t.Errorf("wanted no createRecord calls, got %d", n)
+	}
+}
+
+func TestPostVideoProcessingTimeout(t *testing.T) {
+	pds := newFakePDS(t)
+	states := make([]string, 1000)
+	for i := range states {
+		states[i] = "JOB_STATE_ENCODING"
+	}
+	handleVideoService(t, pds, states...)
+	client := pds.newClient(t, WithVideoService(pds.URL))
+	client.videoPollInterval = time.Millisecond
+	client.videoProcessingTimeout = 20 * time.Millisecond
+
+	pb := NewPostBuilder("A screen recording").AddVideoFromReader(bytes.NewReader(makeMP4(1280, 720)), "")
+	_, err := client.Post(pb)
+	if err == nil || !strings.Contains(err.Error(), "did not finish") {
+		t.Fatalf("wanted processing timeout, got %v", err)
+	}
+	if n := pds.count("com.atproto.repo.createRecord"); n != 0 {
+		t.Errorf("wanted no createRecord calls, got %d", n)
+	}
+}
+
+func TestPostVideoAlreadyUploaded(t *testing.T) {
+	pds := newFakePDS(t)
+	handleVideoService(t, pds, "JOB_STATE_ENCODING")
+	pds.on("app.bsky.video.uploadVideo", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusConflict, map[string]string{
+			"error":   "already_exists",
+			"message": "Video already processed",
+			"jobId":   "job1",
+			"did":     pds.did,
+			"state":   videoJobCompleted,
+		})
+	})
+	client := pds.newClient(t, WithVideoService(pds.URL))
+	client.videoPollInterval = time.Millisecond
+
+	uri, err := client.Post(NewPostBuilder("Again").AddVideoFromReader(bytes.NewReader(makeMP4(1280, 720)), ""))
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	if n := pds.count("app.bsky.video.getJobStatus"); n != 2 {
+		t.Errorf("wanted 2 job status checks, got %d", n)
+	}
+	video, _ := embedOf(t, pds.record(uri))["video"].(map[string]any)
+	if ref, _ := video["ref"].(map[string]any); ref["$link"] != "bafyvideo" {
+		t.Errorf("wanted video blob bafyvideo, got %v", video)
+	}
+}
+
+func TestPostVideoTooLong(t *testing.T) {
+	pds := newFakePDS(t)
+	handleVideoService(t, pds)
+	client := pds.newClient(t, WithVideoService(pds.URL))
+
+	data := makeMP4WithDuration(1280, 720, MaxVideoDuration+time.Second)
+	_, err := client.Post(NewPostBuilder("A long video").AddVideoFromReader(bytes.NewReader(data), ""))
+	if !errors.Is(err, ErrInvalidPost) {
+		t.Fatalf("wanted ErrInvalidPost, got %v", err)
+	}
+	if n := pds.count("app.bsky.video.uploadVideo"); n != 0 {
+		t.Errorf("wanted no uploadVideo calls, got %d", n)
+	}
+}
+
+func TestValidateVideoFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(MaxVideoBytes + 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewPostBuilder("Video").AddVideoFromPath(path, "").Validate(); !errors.Is(err, ErrInvalidPost) {
+		t.Errorf("wanted ErrInvalidPost, got %v", err)
+	}
+}
+
+func TestPostVideoMissingFile(t *testing.T) {
+	pds := newFakePDS(t)
+	client := pds.newClient(t, WithVideoService(pds.URL))
+
+	_, err := client.Post(NewPostBuilder("A screen recording").AddVideoFromPath("./test-data/missing.mp4", ""))
+	if err == nil {
+		t.Fatal("wanted error, got nil")
+	}
+	if n := pds.count("app.bsky.video.uploadVideo"); n != 0 {
+		t.Errorf("wanted no uploadVideo calls, got %d", n)
+	}
+}
@@ -0,0 +1,242 @@
+package ltbsky
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Sentinel errors for common XRPC error names. They match any *XRPCError with
+// the same name when used with errors.Is.
+var (
+	ErrAuthenticationRequired = &XRPCError{Name: "AuthenticationRequired"}
+	ErrAccountTakedown        = &XRPCError{Name: "AccountTakedown"}
+	ErrExpiredToken           = &XRPCError{Name: "ExpiredToken"}
+	ErrInvalidToken           = &XRPCError{Name: "InvalidToken"}
+	ErrInvalidRequest         = &XRPCError{Name: "InvalidRequest"}
+	ErrInvalidSwap            = &XRPCError{Name: "InvalidSwap"}
+	ErrRateLimitExceeded      = &XRPCError{Name: "RateLimitExceeded"}
+	ErrRecordNotFound         = &XRPCError{Name: "RecordNotFound"}
+)
+
+// XRPCError is returned when the server answers an XRPC request with an error
+// status. Use errors.As to inspect it, or errors.Is with one of the sentinel
+// errors such as ErrExpiredToken.
+type XRPCError struct {
+	// NSID is the XRPC method that failed, e.g. "com.atproto.repo.createRecord".
+	NSID string
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Name is the "error" field of the response body, e.g. "ExpiredToken".
+	Name string
+	// Message is the "message" field of the response body.
+	Message string
+	// RateLimit holds the rate-limit headers of the response, or nil if the
+	// server did not send any.
+	RateLimit *RateLimit
+	// RetryAfter is the wait requested by the Retry-After header, or zero.
+	RetryAfter time.Duration
+
+	// body is the response body, for errors that carry more than a name and
+	// a message
+	body []byte
+}
+
+// RateLimit describes the rate-limit headers sent with an XRPC response.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+	Policy    string
+}
+
+func (e *XRPCError) Error() string {
+	return fmt.Sprintf("%s failed with status code: %d error: %s message: %s", e.NSID, e.StatusCode, e.Name, e.Message)
+}
+
+// Is reports whether target is a sentinel *XRPCError with the same name. A
+// response with status 429 also matches ErrRateLimitExceeded.
+func (e *XRPCError) Is(target error) bool {
+	t, ok := target.(*XRPCError)
+	if !ok || t.Name == "" {
+		return false
+	}
+	if t.Name == ErrRateLimitExceeded.Name && e.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return t.Name == e.Name
+}
+
+// xrpcRequest describes a single XRPC call.
+type xrpcRequest struct {
+	method string // "GET" for queries, "POST" for procedures
+	// host is the server the request is sent to. It defaults to the
+	// client's server.
+	host   string
+	nsid   string
+	params url.Values
+	// body is marshaled as JSON unless rawBody is set.
+	body        any
+	rawBody     []byte
+	contentType string
+	token       string
+	// proxy, if set, is sent in the atproto-proxy header to have the server
+	// forward the request to another service, such as ChatService.
+	proxy string
+	// unsafeRetry is set for procedures that cannot be repeated safely. They
+	// are only retried after a rate limit, which the server enforces before
+	// acting on the request.
+	unsafeRetry bool
+	// beforeRetry, if set, is called before the request is retried. It
+	// returns true if the previous attempt turns out to have succeeded, in
+	// which case it must also fill in the response.
+	beforeRetry func(ctx context.Context, out any) bool
+}
+
+// xrpc sends r to the server and unmarshals a successful response into out,
+// which may be nil. Error responses are returned as *XRPCError. Failed
+// requests are retried according to the client's RetryPolicy.
+func (c *Client) xrpc(ctx context.Context, r *xrpcRequest, out any) error {
+	for attempt := 1; ; attempt++ {
+		err := c.xrpcOnce(ctx, r, out)
+		if err == nil {
+			return nil
+		}
+		delay, ok := c.retry.delay(attempt, err)
+		if !ok || (r.unsafeRetry && !errors.Is(err, ErrRateLimitExceeded)) {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		if r.beforeRetry != nil && r.beforeRetry(ctx, out) {
+			return nil
+		}
+	}
+}
+
+// xrpcOnce makes a single attempt at sending r.
+func (c *Client) xrpcOnce(ctx context.Context, r *xrpcRequest, out any) (err error) {
+	host := r.host
+	if host == "" {
+		host = c.server
+	}
+	u := fmt.Sprintf("%s/xrpc/%s", host, r.nsid)
+	if len(r.params) > 0 {
+		u += "?" + r.params.Encode()
+	}
+	contentType := r.contentType
+	data := r.rawBody
+	if data == nil && r.body != nil {
+		b, err := json.Marshal(r.body)
+		if err != nil {
+			return fmt.Errorf("error marshaling request body: %w", err)
+		}
+		data = b
+		contentType = "application/json"
+	}
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if r.proxy != "" {
+		req.Header.Set("atproto-proxy", r.proxy)
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &transportError{err: err}
+	}
+	defer func() {
+		err = errors.Join(err, resp.Body.Close())
+	}()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newXRPCError(r.nsid, resp, b)
+	}
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return nil
+}
+
+// newXRPCError creates an *XRPCError from an error response and its body.
+func newXRPCError(nsid string, resp *http.Response, body []byte) *XRPCError {
+	e := &XRPCError{
+		NSID:       nsid,
+		StatusCode: resp.StatusCode,
+		RateLimit:  parseRateLimit(resp.Header),
+		RetryAfter: parseRetryAfter(resp.Header),
+		body:       body,
+	}
+	var errorResponse struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &errorResponse); err == nil {
+		e.Name = errorResponse.Error
+		e.Message = errorResponse.Message
+	}
+	return e
+}
+
+// parseRateLimit reads the RateLimit-* headers, returning nil if none are set.
+func parseRateLimit(h http.Header) *RateLimit {
+	if h.Get("RateLimit-Limit") == "" && h.Get("RateLimit-Remaining") == "" && h.Get("RateLimit-Reset") == "" {
+		return nil
+	}
+	rl := &RateLimit{Policy: h.Get("RateLimit-Policy")}
+	if v, err := strconv.Atoi(h.Get("RateLimit-Limit")); err == nil {
+		rl.Limit = v
+	}
+	if v, err := strconv.Atoi(h.Get("RateLimit-Remaining")); err == nil {
+		rl.Remaining = v
+	}
+	if v, err := strconv.ParseInt(h.Get("RateLimit-Reset"), 10, 64); err == nil {
+		rl.Reset = time.Unix(v, 0)
+	}
+	return rl
+}
+
+// parseRetryAfter reads the Retry-After header, which holds either a number
+// of seconds or an HTTP date. It returns zero if the header is missing.
+func parseRetryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
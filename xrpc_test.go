@@ -0,0 +1,135 @@
+package ltbsky
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestXRPCErrorIs(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    *XRPCError
+		target error
+		want   bool
+	}{
+		{
+			name:   "Same name",
+			err:    &XRPCError{StatusCode: http.StatusBadRequest, Name: "ExpiredToken"},
+			target: ErrExpiredToken,
+			want:   true,
+		},
+		{
+			name:   "Different name",
+			err:    &XRPCError{StatusCode: http.StatusBadRequest, Name: "InvalidRequest"},
+			target: ErrExpiredToken,
+			want:   false,
+		},
+		{
+			name:   "Status 429 without a name",
+			err:    &XRPCError{StatusCode: http.StatusTooManyRequests},
+			target: ErrRateLimitExceeded,
+			want:   true,
+		},
+		{
+			name:   "Not an XRPC error",
+			err:    &XRPCError{StatusCode: http.StatusBadRequest, Name: "InvalidRequest"},
+			target: errors.New("InvalidRequest"),
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("wanted errors.Is to be %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuthReturnsXRPCError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, err := w.Write([]byte(`{"error": "AuthenticationRequired", "message": "Invalid identifier or password"}`))
+		if err != nil {
+			return
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test.handle", "test.password")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	_, err = client.Post(NewPostBuilder("Hello, world!"))
+	if !errors.Is(err, ErrAuthenticationRequired) {
+		t.Fatalf("wanted ErrAuthenticationRequired, got %v", err)
+	}
+	var xe *XRPCError
+	if !errors.As(err, &xe) {
+		t.Fatalf("wanted *XRPCError, got %T", err)
+	}
+	if xe.NSID != "com.atproto.server.createSession" {
+		t.Errorf("wanted NSID 'com.atproto.server.createSession', got '%s'", xe.NSID)
+	}
+	if xe.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wanted status code %d, got %d", http.StatusUnauthorized, xe.StatusCode)
+	}
+	if xe.Message != "Invalid identifier or password" {
+		t.Errorf("wanted message 'Invalid identifier or password', got '%s'", xe.Message)
+	}
+}
+
+func TestPostReturnsRateLimitError(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte(`{"accessJwt": "test.token", "did": "did:plc:test"}`))
+			if err != nil {
+				return
+			}
+		default:
+			w.Header().Set("RateLimit-Limit", "5000")
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			w.Header().Set("RateLimit-Policy", "5000;w=3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, err := w.Write([]byte(`{"error": "RateLimitExceeded", "message": "Rate Limit Exceeded"}`))
+			if err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test.handle", "test.password")
+	if err != nil {
+		t.Fatalf("wanted no error, got %v", err)
+	}
+	_, err = client.Post(NewPostBuilder("Hello, world!"))
+	if !errors.Is(err, ErrRateLimitExceeded) {
+		t.Fatalf("wanted ErrRateLimitExceeded, got %v", err)
+	}
+	var xe *XRPCError
+	if !errors.As(err, &xe) {
+		t.Fatalf("wanted *XRPCError, got %T", err)
+	}
+	if xe.NSID != "com.atproto.repo.createRecord" {
+		t.Errorf("wanted NSID 'com.atproto.repo.createRecord', got '%s'", xe.NSID)
+	}
+	if xe.RateLimit == nil {
+		t.Fatal("wanted rate limit, got nil")
+	}
+	if xe.RateLimit.Limit != 5000 || xe.RateLimit.Remaining != 0 || xe.RateLimit.Policy != "5000;w=3600" {
+		t.Errorf("wanted rate limit 5000/0/5000;w=3600, got %+v", xe.RateLimit)
+	}
+	if !xe.RateLimit.Reset.Equal(reset) {
+		t.Errorf("wanted reset %v, got %v", reset, xe.RateLimit.Reset)
+	}
+}